@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// A source of odds. Fetch runs one scraping cycle and returns the normalized
+// matches per market family name; everything after that (save, compare,
+// notify) is shared by every bookmaker.
+type Bookmaker interface {
+	Name() string            // short name used for cross-book comparison
+	ID() string              // unique per watcher, used for its state files
+	Markets() []MarketFamily // families this source fills
+	Silent() bool            // only used for comparison, no new bet notifications
+	Fetch(lg *slog.Logger) (FetchResult, error)
+	NextCycle(now time.Time) time.Duration // wait before the next Fetch
+}
+
+// One cycle of a bookmaker: the followed matches per market family, and the
+// status of every listed match, followed or not
+type FetchResult struct {
+	Families map[string][]MatchData
+	Matches  []MatchStatus
+}
+
+// A failed cycle. Soft errors (unreadable payload) are retried after a short
+// pause without counting towards the 3 failures that put the watcher on a longer pause.
+type fetchError struct {
+	stage   string
+	proxy   string
+	message string
+	err     error
+	soft    bool
+}
+
+func (e *fetchError) Error() string {
+	if e.err == nil {
+		return e.message
+	}
+	return fmt.Sprintf("%s: %v", e.message, e.err)
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
+
+// Wait after a failed cycle, after the first soft error, and the longest pause of a failing watcher
+const (
+	FAILURE_PAUSE     = 10 * time.Second
+	SOFT_RETRY_PAUSE  = 2 * time.Second
+	MAX_FAILURE_PAUSE = 10 * time.Minute
+)
+
+// Pause after the n-th failure in a row (n >= 3): doubles each time up to MAX_FAILURE_PAUSE
+func failureBackoff(n int) time.Duration {
+	pause := FAILURE_PAUSE
+	for i := 2; i < n && pause < MAX_FAILURE_PAUSE; i++ {
+		pause *= 2
+	}
+	return min(pause, MAX_FAILURE_PAUSE)
+}
+
+// Pause after the n-th soft error in a row: doubles from SOFT_RETRY_PAUSE up to MAX_FAILURE_PAUSE
+func softBackoff(n int) time.Duration {
+	pause := SOFT_RETRY_PAUSE
+	for i := 1; i < n && pause < MAX_FAILURE_PAUSE; i++ {
+		pause *= 2
+	}
+	return min(pause, MAX_FAILURE_PAUSE)
+}
+
+// Drop the alert state kept for matches that are over
+func forgetMatches(matches []Match) {
+	for _, m := range matches {
+		crossBooks.forget(m.MatchID)
+		pricingAlerts.forget(m.Lien)
+		preferences.forget(m.MatchID)
+	}
+}
+
+// Scrape a bookmaker forever: fetch a cycle, save per family, notify new bets
+// and compare with the other books
+func runWatcher(source Bookmaker, logger *slog.Logger) {
+	markets := source.Markets()
+	lifecycle := newLifecycleTracker(source.ID())
+
+	retryCount := 0
+	softCount := 0
+	loopCount := 0
+
+	// Count a real (non-proxy) failure and return how long to wait before the
+	// next cycle. After 3 in a row only this watcher backs off, the others and
+	// the bot keep running.
+	failOrRetry := func(lg *slog.Logger, err error, msg string) time.Duration {
+		if isProxyError(err) {
+			lg.Warn("⚠️ Erreur proxy - changement...", "err", err)
+			digest.proxySwitched()
+			return FAILURE_PAUSE
+		}
+		lg.Error(msg, "err", err)
+		retryCount++
+		if retryCount < 3 {
+			return FAILURE_PAUSE
+		}
+		pause := failureBackoff(retryCount)
+		lg.Error(fmt.Sprintf("❌ Échec %d fois de suite, pause de %s", retryCount, pause), "failures", retryCount, "pause", pause)
+		return pause
+	}
+
+	for {
+		loopCount++
+		digest.cycleStarted()
+
+		cycleLog := logger.With("trace", newTraceID(), "cycle", loopCount)
+		cycleLog.Info(fmt.Sprintf("🔄 Cycle #%d démarré", loopCount))
+
+		fetched, err := source.Fetch(cycleLog)
+		if err != nil {
+			fe, ok := err.(*fetchError)
+			if !ok {
+				fe = &fetchError{stage: "fetch", message: "❌ Échec récupération", err: err}
+			}
+			failLog := cycleLog.With("stage", fe.stage)
+			if fe.proxy != "" {
+				failLog = failLog.With("proxy", fe.proxy)
+			}
+			if fe.soft {
+				softCount++
+				failLog.Warn(fe.message, "err", fe.err, "retries", softCount)
+				time.Sleep(softBackoff(softCount))
+				continue
+			}
+			time.Sleep(failOrRetry(failLog, fe.err, fe.message))
+			continue
+		}
+
+		forgetMatches(lifecycle.update(fetched.Matches, cycleLog.With("stage", "lifecycle")))
+		liveMatches.set(source.ID(), lifecycle.live())
+
+		// save matches, compare with previous data and notify for new bets - per family
+		var allResults []MatchData
+		for _, family := range markets {
+			results := fetched.Families[family.Name]
+			for i := range results {
+				annotateMargins(&results[i])
+				annotateFairPrices(&results[i])
+			}
+			allResults = append(allResults, results...)
+
+			output, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				cycleLog.Error("Failed to marshal final output", "stage", "save", "family", family.Name, "err", err)
+				continue
+			}
+			if err := os.WriteFile(family.currentFile(), output, 0644); err != nil {
+				cycleLog.Error("Failed to write output JSON", "stage", "save", "family", family.Name, "err", err)
+				continue
+			}
+
+			cycleLog.Debug("Saved bets", "stage", "save", "family", family.Name, "file", family.currentFile())
+
+			// Matches that started since the last cycle are saved but not diffed nor alerted on
+			prematch := lifecycle.prematch(results)
+
+			familyLog := cycleLog.With("stage", "notify", "family", family.Name)
+			if !source.Silent() {
+				recentMatches.update(family, results)
+				clv.observe(family, prematch, familyLog)
+				alerted := family.alertMatches(prematch)
+				compareAndNotify(family, alerted, familyLog)
+				checkMargins(family, alerted, familyLog)
+				checkValue(family, alerted, familyLog)
+				checkConsistency(family, alerted, familyLog)
+				checkRules(family, alerted, familyLog)
+				preferences.observe(family, prematch, familyLog)
+			}
+			crossBooks.update(family, source.Name(), prematch, familyLog)
+
+			// Save current as past for next iteration
+			saveAsPast(family)
+		}
+
+		// Reset retry counts on success
+		retryCount, softCount = 0, 0
+		digest.cycleSucceeded(allResults)
+
+		// Count total bets
+		totalBets := 0
+		for _, m := range allResults {
+			totalBets += len(m.Bet)
+		}
+
+		cycleLog.Info(fmt.Sprintf("✅ OK - %d matchs, %d paris", len(allResults), totalBets), "stage", "done", "matches", len(allResults), "bets", totalBets)
+
+		time.Sleep(source.NextCycle(time.Now()))
+	}
+}
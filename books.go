@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Winamax site watched with its own session, language and proxies
+type BookConfig struct {
+	Name           string `json:"name"`            // "fr", "es"
+	BaseURL        string `json:"base_url"`        // https://www.winamax.fr
+	SocketURL      string `json:"socket_url"`      // socket.io host serving the odds
+	Language       string `json:"language"`        // socket.io language parameter (FR, ES)
+	AcceptLanguage string `json:"accept_language"` // Accept-Language header
+	SportPage      string `json:"sport_page"`      // sport page path, formatted with the sport ID
+	MatchPage      string `json:"match_page"`      // match link path, formatted with the match ID
+	Proxies        string `json:"proxies"`         // proxy file for this book
+	Silent         bool   `json:"silent"`          // only used for comparison, no new bet notifications
+}
+
+// Known books - anything left empty in the config is taken from here
+var knownBooks = map[string]BookConfig{
+	"fr": {
+		Name:           "fr",
+		BaseURL:        URL_BASE,
+		SocketURL:      SocketURL,
+		Language:       "FR",
+		AcceptLanguage: "fr-FR,fr;q=0.9",
+		SportPage:      FrenchPage,
+		MatchPage:      "/paris-sportifs/match/%s",
+		Proxies:        "proxy.txt",
+	},
+	"es": {
+		Name:           "es",
+		BaseURL:        "https://www.winamax.es",
+		SocketURL:      "https://sports-eu-west-3.winamax.es",
+		Language:       "ES",
+		AcceptLanguage: "es-ES,es;q=0.9",
+		SportPage:      SpanishPage,
+		MatchPage:      "/apuestas-deportivas/match/%s",
+		Proxies:        "proxy_es.txt",
+		Silent:         true,
+	},
+}
+
+func defaultBooks() []BookConfig {
+	return []BookConfig{knownBooks["fr"]}
+}
+
+// Fill empty fields from the known book of the same name
+func (b *BookConfig) normalize() {
+	known, ok := knownBooks[b.Name]
+	if !ok {
+		known = knownBooks["fr"]
+	}
+	if b.BaseURL == "" {
+		b.BaseURL = known.BaseURL
+	}
+	if b.SocketURL == "" {
+		b.SocketURL = known.SocketURL
+	}
+	if b.Language == "" {
+		b.Language = known.Language
+	}
+	if b.AcceptLanguage == "" {
+		b.AcceptLanguage = known.AcceptLanguage
+	}
+	if b.SportPage == "" {
+		b.SportPage = known.SportPage
+	}
+	if b.MatchPage == "" {
+		b.MatchPage = known.MatchPage
+	}
+	if b.Proxies == "" {
+		b.Proxies = known.Proxies
+	}
+}
+
+// The French book keeps the historical file names
+func (b BookConfig) isDefault() bool {
+	return b.Name == "" || b.Name == "fr"
+}
+
+func (b BookConfig) matchLink(matchID string) string {
+	return b.BaseURL + fmt.Sprintf(b.MatchPage, matchID)
+}
+
+// Copy of the sport's families bound to a book, so each book gets its own files
+func (s SportConfig) marketsFor(book BookConfig) []MarketFamily {
+	families := make([]MarketFamily, len(s.Markets))
+	for i, family := range s.Markets {
+		if !book.isDefault() {
+			family.book = book.Name
+		}
+		families[i] = family
+	}
+	return families
+}
+
+// Cross-book comparison settings
+type BookCompareConfig struct {
+	Channel     string  `json:"channel"`       // where differences are posted (default NEW_BETS_CHANNEL)
+	MinCoteDiff float64 `json:"min_cote_diff"` // ignore cote differences smaller than this
+}
+
+// Latest results of every book, per sport+family, and the differences already alerted
+type bookSnapshots struct {
+	mu      sync.Mutex
+	results map[string]map[string][]MatchData // family key -> book -> results
+	alerted map[string]string                 // match|bet -> signature of the last alert
+}
+
+var crossBooks = &bookSnapshots{
+	results: make(map[string]map[string][]MatchData),
+	alerted: make(map[string]string),
+}
+
+// Forget the differences alerted on a match
+func (s *bookSnapshots) forget(matchID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.alerted {
+		if strings.HasPrefix(key, matchID+"|") {
+			delete(s.alerted, key)
+		}
+	}
+}
+
+// Store a book's results for a family and compare them with the other books
+func (s *bookSnapshots) update(family MarketFamily, bookName string, results []MatchData, lg *slog.Logger) {
+	if len(config.Books) < 2 {
+		return
+	}
+
+	key := family.sport + "|" + family.Name
+	s.mu.Lock()
+	if s.results[key] == nil {
+		s.results[key] = make(map[string][]MatchData)
+	}
+	s.results[key][bookName] = results
+
+	var alerts []bookDiff
+	for otherBook, otherResults := range s.results[key] {
+		if otherBook == bookName {
+			continue
+		}
+		for _, diff := range diffBooks(bookName, results, otherBook, otherResults) {
+			alertKey := diff.matchID + "|" + diff.betKey + "|" + strings.Join(sortedPair(bookName, otherBook), "/")
+			if s.alerted[alertKey] == diff.signature {
+				continue
+			}
+			s.alerted[alertKey] = diff.signature
+			alerts = append(alerts, diff)
+		}
+	}
+	s.mu.Unlock()
+
+	channel := config.BookCompare.Channel
+	for _, alert := range alerts {
+		if preferences.muted(channel, alert.match) {
+			continue
+		}
+		if outcome, err := deliverAlert(lg, channel, "écart entre sites", alert.match, alert.message, PARSE_MODE_HTML, alertKeyboard(alert.match)); err != nil {
+			lg.Error("Failed to send book comparison alert", "err", err)
+		} else if outcome == DELIVERY_SENT {
+			lg.Info("Book comparison alert sent")
+		}
+	}
+}
+
+func sortedPair(a, b string) []string {
+	pair := []string{a, b}
+	sort.Strings(pair)
+	return pair
+}
+
+type bookDiff struct {
+	match     Match // as listed on the first book
+	matchID   string
+	betKey    string
+	signature string // prices on both sides, to alert again only when they move
+	message   string
+}
+
+// Compare the Over/Under lines and ladder cotes of the same matches in two books.
+// Bet names are translated, so bets are matched on the Winamax bet type ID and
+// the player they belong to.
+func diffBooks(bookA string, resultsA []MatchData, bookB string, resultsB []MatchData) []bookDiff {
+	byMatch := make(map[string]MatchData)
+	for _, m := range resultsB {
+		if m.Match.MatchID != "" {
+			byMatch[m.Match.MatchID] = m
+		}
+	}
+
+	minDiff := config.BookCompare.MinCoteDiff
+	var diffs []bookDiff
+	for _, matchA := range resultsA {
+		matchB, ok := byMatch[matchA.Match.MatchID]
+		if !ok || matchA.Match.MatchID == "" {
+			continue
+		}
+
+		betsA := groupByBookKey(matchA)
+		betsB := groupByBookKey(matchB)
+		for key, listA := range betsA {
+			listB := betsB[key]
+			// Alternative lines share a key - only compare unambiguous bets
+			if len(listA) != 1 || len(listB) != 1 {
+				continue
+			}
+			a, b := listA[0], listB[0]
+
+			var lines []string
+			switch {
+			case a.isOverUnder() && b.isOverUnder():
+				// A different cut, or prices apart by more than min_cote_diff at the same one
+				if a.Cut != b.Cut || math.Abs(a.Plus-b.Plus) > minDiff+1e-9 || math.Abs(a.Moins-b.Moins) > minDiff+1e-9 {
+					lines = append(lines,
+						fmt.Sprintf("%s : + %.1f @ %.2f / - %.1f @ %.2f", strings.ToUpper(bookA), a.Cut, roundCote(a.Plus), a.Cut, roundCote(a.Moins)),
+						fmt.Sprintf("%s : + %.1f @ %.2f / - %.1f @ %.2f", strings.ToUpper(bookB), b.Cut, roundCote(b.Plus), b.Cut, roundCote(b.Moins)))
+				}
+			case a.isLadder() && b.isLadder():
+				cotesB := make(map[float64]float64)
+				for _, opt := range b.Options {
+					cotesB[opt.Seuil] = opt.Cote
+				}
+				for _, opt := range a.Options {
+					coteB, ok := cotesB[opt.Seuil]
+					if ok && math.Abs(opt.Cote-coteB) > minDiff+1e-9 {
+						lines = append(lines, fmt.Sprintf("%.0f : %s @ %.2f / %s @ %.2f",
+							opt.Seuil, strings.ToUpper(bookA), roundCote(opt.Cote), strings.ToUpper(bookB), roundCote(coteB)))
+					}
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+
+			var message strings.Builder
+			message.WriteString(fmt.Sprintf("⚖️ <b>%s</b>\n", html.EscapeString(matchA.Match.displayTitle())))
+			message.WriteString(fmt.Sprintf("<b>%s</b> / <b>%s</b>\n", html.EscapeString(a.Type), html.EscapeString(b.Type)))
+			message.WriteString(html.EscapeString(strings.Join(lines, "\n")))
+			message.WriteString(fmt.Sprintf("\n\n🔗 <a href=\"%s\">%s</a> | <a href=\"%s\">%s</a>",
+				matchA.Match.Lien, strings.ToUpper(bookA), matchB.Match.Lien, strings.ToUpper(bookB)))
+
+			diffs = append(diffs, bookDiff{
+				match:     matchA.Match,
+				matchID:   matchA.Match.MatchID,
+				betKey:    key,
+				signature: generateBetSignature(a) + "#" + generateBetSignature(b),
+				message:   message.String(),
+			})
+		}
+	}
+	return diffs
+}
+
+// Key bets by Winamax bet type ID and player side, which don't depend on the language
+func groupByBookKey(m MatchData) map[string][]Bet {
+	grouped := make(map[string][]Bet)
+	for _, bet := range m.Bet {
+		if bet.BetTypeID == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%d|%d", bet.BetTypeID, betSide(bet, m.Match))
+		grouped[key] = append(grouped[key], bet)
+	}
+	return grouped
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commands read from the Telegram bot
+type BotConfig struct {
+	Enabled    bool    `json:"enabled"`     // poll the bot for commands and button presses (off by default: getUpdates takes them from any other user of the token)
+	LedgerFile string  `json:"ledger_file"` // bets recorded with /bet
+	Allowed    []int64 `json:"allowed"`     // user or chat IDs that may record and settle bets (none when empty)
+}
+
+// Whether a user (0 for channel posts) or a chat may change the ledger
+func (c BotConfig) allows(userID, chatID int64) bool {
+	return (userID != 0 && slices.Contains(c.Allowed, userID)) || slices.Contains(c.Allowed, chatID)
+}
+
+const NOT_ALLOWED = "⛔ Vous n'êtes pas autorisé à enregistrer ou régler des paris"
+
+type tgUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+type tgEntity struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type tgMessage struct {
+	MessageID int64 `json:"message_id"`
+	Chat      struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"` // private, group, supergroup or channel
+	} `json:"chat"`
+	From       *tgUser    `json:"from"`
+	Text       string     `json:"text"`
+	Entities   []tgEntity `json:"entities"`
+	ReplyTo    *tgMessage `json:"reply_to_message"`
+	AuthorName string     `json:"author_signature"` // channel posts have no From
+}
+
+type tgCallback struct {
+	ID      string     `json:"id"`
+	From    tgUser     `json:"from"`
+	Message *tgMessage `json:"message"`
+	Data    string     `json:"data"`
+}
+
+type tgUpdate struct {
+	UpdateID      int64       `json:"update_id"`
+	Message       *tgMessage  `json:"message"`
+	ChannelPost   *tgMessage  `json:"channel_post"`
+	CallbackQuery *tgCallback `json:"callback_query"`
+}
+
+// One inline keyboard button sending data back to the bot
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// Send a message with an inline keyboard under it
+func sendTelegramKeyboard(chatID, message string, keyboard [][]inlineButton) error {
+	return sendTelegramAlert(chatID, message, PARSE_MODE_HTML, keyboard, false)
+}
+
+// Send a message with its keyboard (if any), silently when asked: Telegram
+// delivers it without sound or vibration
+func sendTelegramAlert(chatID, message, parseMode string, keyboard [][]inlineButton, silent bool) error {
+	data := url.Values{}
+	data.Set("chat_id", chatID)
+	data.Set("text", message)
+	data.Set("parse_mode", parseMode)
+	data.Set("disable_web_page_preview", "true")
+	if silent {
+		data.Set("disable_notification", "true")
+	}
+	if len(keyboard) > 0 {
+		markup, err := json.Marshal(map[string]interface{}{"inline_keyboard": keyboard})
+		if err != nil {
+			return err
+		}
+		data.Set("reply_markup", string(markup))
+	}
+
+	_, err := callTelegram("sendMessage", data, 10*time.Second)
+	return err
+}
+
+// Ask for a /bet answer to a message; outside channels Telegram opens the reply
+// field with the command as placeholder
+func sendTelegramPrompt(msg *tgMessage, message string) error {
+	data := url.Values{}
+	data.Set("chat_id", strconv.FormatInt(msg.Chat.ID, 10))
+	data.Set("text", message)
+	data.Set("parse_mode", "HTML")
+	data.Set("disable_web_page_preview", "true")
+	data.Set("reply_to_message_id", strconv.FormatInt(msg.MessageID, 10))
+	if msg.Chat.Type != "channel" {
+		data.Set("reply_markup", `{"force_reply":true,"selective":true,"input_field_placeholder":"/bet 12@2.40 25€"}`)
+	}
+	_, err := callTelegram("sendMessage", data, 10*time.Second)
+	return err
+}
+
+// Acknowledge a button press, with a short popup text
+func answerCallback(id, text string) error {
+	data := url.Values{}
+	data.Set("callback_query_id", id)
+	data.Set("text", text)
+	_, err := callTelegram("answerCallbackQuery", data, 10*time.Second)
+	return err
+}
+
+// Long-poll the bot for commands and button presses until the process exits
+func runBot(lg *slog.Logger) {
+	var offset int64
+	for {
+		data := url.Values{}
+		data.Set("offset", strconv.FormatInt(offset, 10))
+		data.Set("timeout", "30")
+		data.Set("allowed_updates", `["message","channel_post","callback_query"]`)
+
+		body, err := callTelegram("getUpdates", data, 40*time.Second)
+		if err != nil {
+			lg.Warn("Failed to get bot updates", "err", err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		var resp struct {
+			Result []tgUpdate `json:"result"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			lg.Warn("Failed to parse bot updates", "err", err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		for _, update := range resp.Result {
+			offset = update.UpdateID + 1
+			switch {
+			case update.CallbackQuery != nil:
+				handleCallback(update.CallbackQuery, lg)
+			case update.Message != nil:
+				handleCommand(update.Message, lg)
+			case update.ChannelPost != nil:
+				handleCommand(update.ChannelPost, lg)
+			}
+		}
+	}
+}
+
+// Name of whoever sent a message
+func (m *tgMessage) sender() string {
+	if m.From != nil {
+		if m.From.Username != "" {
+			return m.From.Username
+		}
+		return m.From.FirstName
+	}
+	return m.AuthorName
+}
+
+var matchIDRegexp = regexp.MustCompile(`/match/(\d+)`)
+
+// Match ID of the notification a message replies to, read from its link
+func (m *tgMessage) repliedMatchID() string {
+	if m.ReplyTo == nil {
+		return ""
+	}
+	for _, entity := range m.ReplyTo.Entities {
+		if found := matchIDRegexp.FindStringSubmatch(entity.URL); found != nil {
+			return found[1]
+		}
+	}
+	return ""
+}
+
+func handleCommand(msg *tgMessage, lg *slog.Logger) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return
+	}
+	// "/pnl@winamax_bot" in groups
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := fields[1:]
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+
+	var userID int64
+	if msg.From != nil {
+		userID = msg.From.ID
+	}
+	var reply string
+	var keyboard [][]inlineButton
+	switch command {
+	case "/bet", "/settle", "/result":
+		if !config.Bot.allows(userID, msg.Chat.ID) {
+			lg.Warn("Ledger command refused", "command", command, "user", userID, "chat", chatID)
+			reply = NOT_ALLOWED
+			break
+		}
+		switch command {
+		case "/bet":
+			reply = ledger.commandBet(msg.repliedMatchID(), args, msg.sender())
+		case "/settle":
+			reply = ledger.commandSettle(args)
+		case "/result":
+			reply = ledger.commandResult(args)
+		}
+	case "/pnl":
+		reply = ledger.report(false)
+	case "/roi":
+		reply = ledger.report(true)
+	case "/prefs":
+		reply, keyboard = preferences.report(chatID)
+	default:
+		return
+	}
+
+	if err := sendTelegramKeyboard(chatID, reply, keyboard); err != nil {
+		lg.Error("Failed to answer bot command", "command", command, "err", err)
+	}
+}
+
+// Buttons carry "bet|matchId|betTypeId|seuil|cote|mise"
+func betCallbackData(matchID string, bet Bet, opt Option, stake float64) string {
+	return fmt.Sprintf("bet|%s|%d|%g|%.2f|%.0f", matchID, bet.BetTypeID, opt.Seuil, roundCote(opt.Cote), stake)
+}
+
+func handleCallback(cb *tgCallback, lg *slog.Logger) {
+	parts := strings.Split(cb.Data, "|")
+	name := cb.From.Username
+	if name == "" {
+		name = cb.From.FirstName
+	}
+	text := "Action inconnue"
+	confirm := false // bets are confirmed in the chat, preferences only in the popup
+
+	switch {
+	case len(parts) == 6 && parts[0] == "bet":
+		var chatID int64
+		if cb.Message != nil {
+			chatID = cb.Message.Chat.ID
+		}
+		if !config.Bot.allows(cb.From.ID, chatID) {
+			lg.Warn("Bet button refused", "user", cb.From.ID, "chat", chatID)
+			text = NOT_ALLOWED
+			break
+		}
+		betTypeID, _ := strconv.Atoi(parts[2])
+		seuil, _ := strconv.ParseFloat(parts[3], 64)
+		cote, _ := strconv.ParseFloat(parts[4], 64)
+		stake, _ := strconv.ParseFloat(parts[5], 64)
+		text = ledger.placeFromButton(parts[1], betTypeID, seuil, cote, stake, name)
+		confirm = true
+
+	case len(parts) == 2 && parts[0] == "pick" && cb.Message != nil:
+		text = "Répondez avec /bet seuil@cote mise"
+		m, ok := recentMatches.get(parts[1])
+		if !ok {
+			text = fmt.Sprintf("❌ Match %s inconnu", parts[1])
+			break
+		}
+		prompt := fmt.Sprintf("🎯 <b>%s</b>\nRépondez à ce message avec /bet 12@2.40 25€ (ou +9.5@1.85, -9.5@1.85)\n🔗 <a href=\"%s\">LIEN</a>",
+			html.EscapeString(m.Match.displayTitle()), m.Match.Lien)
+		if err := sendTelegramPrompt(cb.Message, prompt); err != nil {
+			lg.Error("Failed to send bet prompt", "err", err)
+		}
+
+	case cb.Message != nil:
+		chatID := strconv.FormatInt(cb.Message.Chat.ID, 10)
+		reply, handled, err := handlePreferenceCallback(chatID, parts, name)
+		if !handled {
+			break
+		}
+		text = reply
+		if err != nil {
+			lg.Error("Error saving preferences", "file", PREFERENCES_FILE, "err", err)
+			text += " (non sauvegardé)"
+		}
+		lg.Info("Preferences updated", "chat", chatID, "action", parts[0], "by", name)
+	}
+
+	if err := answerCallback(cb.ID, stripTags(text)); err != nil {
+		lg.Warn("Failed to answer button press", "err", err)
+	}
+	if confirm && cb.Message != nil {
+		if err := sendTelegramMessage(strconv.FormatInt(cb.Message.Chat.ID, 10), text); err != nil {
+			lg.Error("Failed to confirm button press", "err", err)
+		}
+	}
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]+>`)
+
+// Popups are plain text
+func stripTags(s string) string {
+	return html.UnescapeString(tagRegexp.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Closing line value of notified and value-alerted prices
+const CLV_FILE = "clv_tracking.json"
+
+// One price we alerted on, followed until the match starts
+type clvEntry struct {
+	MatchID    string    `json:"matchId"`
+	Book       string    `json:"book"`
+	Family     string    `json:"family"`
+	Joueurs    string    `json:"joueurs"`
+	Type       string    `json:"type"`
+	Side       int       `json:"side"`   // 1 or 2 for a player, 0 for the match
+	Market     string    `json:"market"` // paliers, plus or moins
+	Seuil      float64   `json:"seuil"`
+	Source     string    `json:"source"` // notification or value
+	AlertedAt  time.Time `json:"alertedAt"`
+	AlertCote  float64   `json:"alertCote"`
+	MatchStart time.Time `json:"matchStart,omitempty"`
+	Closing    float64   `json:"closing,omitempty"` // last cote seen before the start, 0 when never seen again
+	ClosingAt  time.Time `json:"closingAt,omitempty"`
+	Closed     bool      `json:"closed"`
+}
+
+func (e clvEntry) key() string {
+	return fmt.Sprintf("%s|%s|%s|%d|%s|%s|%g|%s", e.Book, e.MatchID, e.Type, e.Side, e.Market, e.Source, e.Seuil, e.Family)
+}
+
+// Positive when the alerted cote beat the closing cote
+func (e clvEntry) clv() float64 {
+	if e.Closing <= 0 {
+		return 0
+	}
+	return e.AlertCote/e.Closing - 1
+}
+
+func (e clvEntry) marketLabel() string {
+	scope := "joueur"
+	if e.Side == 0 {
+		scope = "match"
+	}
+	return e.Market + " " + scope
+}
+
+type clvTracker struct {
+	mu         sync.Mutex
+	path       string
+	Entries    []clvEntry `json:"entries"`
+	LastReport time.Time  `json:"lastReport"`
+}
+
+var clv = &clvTracker{path: CLV_FILE}
+
+func (t *clvTracker) load() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		t.LastReport = time.Now()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, t)
+}
+
+func (t *clvTracker) save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0644)
+}
+
+// Start following the prices of bets just notified or alerted
+func (t *clvTracker) track(family MarketFamily, match Match, bets []Bet, source string) {
+	if match.MatchID == "" {
+		return
+	}
+	now := time.Now()
+	base := clvEntry{
+		MatchID:   match.MatchID,
+		Book:      family.bookName(),
+		Family:    family.Name,
+		Joueurs:   match.Joueurs,
+		Source:    source,
+		AlertedAt: now,
+	}
+	if match.MatchStart > 0 {
+		base.MatchStart = time.Unix(match.MatchStart, 0)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	known := make(map[string]bool)
+	for _, e := range t.Entries {
+		known[e.key()] = true
+	}
+	add := func(bet Bet, market string, seuil, cote float64) {
+		e := base
+		e.Type = bet.Type
+		e.Side = betSide(bet, match)
+		e.Market = market
+		e.Seuil = seuil
+		e.AlertCote = cote
+		if cote <= 0 || known[e.key()] {
+			return
+		}
+		known[e.key()] = true
+		t.Entries = append(t.Entries, e)
+	}
+	for _, bet := range bets {
+		switch {
+		case bet.isLadder():
+			for _, opt := range bet.Options {
+				add(bet, "paliers", opt.Seuil, opt.Cote)
+			}
+		case bet.isOverUnder():
+			add(bet, "plus", bet.Cut, bet.Plus)
+			add(bet, "moins", bet.Cut, bet.Moins)
+		}
+	}
+	if err := t.save(); err != nil {
+		slog.Warn("Failed to save CLV tracking", "file", t.path, "err", err)
+	}
+}
+
+// Record the latest cotes of followed prices and close the ones whose match has started
+func (t *clvTracker) observe(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	now := time.Now()
+	byMatch := make(map[string]MatchData)
+	for _, m := range results {
+		byMatch[m.Match.MatchID] = m
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := false
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		if e.Closed || e.Book != family.bookName() || e.Family != family.Name {
+			continue
+		}
+		// Without a start time, give up two days after the alert
+		started := (!e.MatchStart.IsZero() && !now.Before(e.MatchStart)) ||
+			(e.MatchStart.IsZero() && now.Sub(e.AlertedAt) > 48*time.Hour)
+		if started {
+			// Never seen after the alert: closed without a closing cote, left out of reports
+			if e.Closing == 0 {
+				e.ClosingAt = now
+			}
+			e.Closed = true
+			changed = true
+			continue
+		}
+		m, ok := byMatch[e.MatchID]
+		if !ok {
+			continue
+		}
+		if cote, ok := e.currentCote(m); ok && cote != e.Closing {
+			e.Closing, e.ClosingAt = cote, now
+			changed = true
+		}
+	}
+
+	// Closed entries are only needed for the reports
+	kept := t.Entries[:0]
+	for _, e := range t.Entries {
+		if !e.Closed || now.Sub(e.ClosingAt) < 60*24*time.Hour {
+			kept = append(kept, e)
+		}
+	}
+	t.Entries = kept
+
+	if changed {
+		if err := t.save(); err != nil {
+			lg.Warn("Failed to save CLV tracking", "file", t.path, "err", err)
+		}
+	}
+}
+
+// The cote of the followed price in a fresh result, if still posted
+func (e clvEntry) currentCote(m MatchData) (float64, bool) {
+	for _, bet := range m.Bet {
+		if bet.Type != e.Type || betSide(bet, m.Match) != e.Side {
+			continue
+		}
+		switch {
+		case e.Market == "paliers" && bet.isLadder():
+			for _, opt := range bet.Options {
+				if opt.Seuil == e.Seuil {
+					return opt.Cote, true
+				}
+			}
+		case e.Market == "plus" && bet.isOverUnder() && bet.Cut == e.Seuil:
+			return bet.Plus, true
+		case e.Market == "moins" && bet.isOverUnder() && bet.Cut == e.Seuil:
+			return bet.Moins, true
+		}
+	}
+	return 0, false
+}
+
+// Matches of a book with followed prices that haven't started, and their families,
+// so the watcher keeps fetching them even when the sport page filters drop them
+func (t *clvTracker) pending(book string) map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	matches := make(map[string][]string)
+	for _, e := range t.Entries {
+		if e.Closed || e.Book != book {
+			continue
+		}
+		if !slices.Contains(matches[e.MatchID], e.Family) {
+			matches[e.MatchID] = append(matches[e.MatchID], e.Family)
+		}
+	}
+	return matches
+}
+
+type clvTotals struct {
+	count int
+	sum   float64
+	beat  int
+}
+
+// Weekly summary of the prices closed since the last report, by market type and
+// source. Prices never seen after the alert have no closing cote and are left out.
+func (t *clvTracker) report() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	since := t.LastReport
+	groups := make(map[string]*clvTotals)
+	var total clvTotals
+	for _, e := range t.Entries {
+		if !e.Closed || e.Closing == 0 || e.ClosingAt.Before(since) {
+			continue
+		}
+		for _, key := range []string{e.marketLabel(), "source " + e.Source} {
+			if groups[key] == nil {
+				groups[key] = &clvTotals{}
+			}
+			groups[key].add(e)
+		}
+		total.add(e)
+	}
+	t.LastReport = now
+	if err := t.save(); err != nil {
+		slog.Warn("Failed to save CLV tracking", "file", t.path, "err", err)
+	}
+	if total.count == 0 {
+		return "", false
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📐 <b>CLV %s → %s</b>\n%s\n\n", since.Format("02/01"), now.Format("02/01"), total.format()))
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		message.WriteString(fmt.Sprintf("%s : %s\n", html.EscapeString(key), groups[key].format()))
+	}
+	return message.String(), true
+}
+
+func (c *clvTotals) add(e clvEntry) {
+	c.count++
+	c.sum += e.clv()
+	if e.clv() > 0 {
+		c.beat++
+	}
+}
+
+func (c clvTotals) format() string {
+	return fmt.Sprintf("%d cotes, CLV moyen %+.1f%%, %.0f%% battent la clôture",
+		c.count, c.sum/float64(c.count)*100, float64(c.beat)/float64(c.count)*100)
+}
+
+// Post the CLV report to the log channel once a week
+func runCLVReport(chatID string, lg *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		clv.mu.Lock()
+		due := time.Since(clv.LastReport) >= 7*24*time.Hour
+		clv.mu.Unlock()
+		if !due {
+			continue
+		}
+		message, ok := clv.report()
+		if !ok {
+			continue
+		}
+		if err := sendTelegramMessage(chatID, message); err != nil {
+			lg.Warn("Failed to send CLV report", "err", err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Optional configuration file - defaults are used for anything it doesn't set
+const CONFIG_FILE = "config.json"
+
+type LogConfig struct {
+	Level                string `json:"level"`                  // debug, info, warn, error
+	Format               string `json:"format"`                 // text or json
+	TelegramLevel        string `json:"telegram_level"`         // minimum level forwarded to LOG_CHANNEL
+	TelegramEverySeconds int    `json:"telegram_every_seconds"` // at most one Telegram log message per interval
+	Digest               bool   `json:"digest"`                 // post a periodic summary instead of every cycle
+	DigestEveryMinutes   int    `json:"digest_every_minutes"`
+}
+
+type Config struct {
+	Log     LogConfig      `json:"log"`
+	Markets []MarketFamily `json:"markets"` // tennis markets, defaults to aces only
+	Sports  []SportConfig  `json:"sports"`  // defaults to tennis with the markets above
+	Books   []BookConfig   `json:"books"`   // defaults to winamax.fr only
+
+	BookCompare BookCompareConfig `json:"book_compare"`
+	Pricing     PricingConfig     `json:"pricing"`
+	Staking     StakingConfig     `json:"staking"`
+	Bot         BotConfig         `json:"bot"`
+	Live        LiveConfig        `json:"live"`
+	Schedule    ScheduleConfig    `json:"schedule"`
+
+	Notifications NotificationConfig `json:"notifications"`
+	AlertRules    RulesConfig        `json:"alert_rules"`
+	Delivery      DeliveryConfig     `json:"delivery"`
+}
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Log: LogConfig{
+			Level:                "info",
+			Format:               "text",
+			TelegramLevel:        "info",
+			TelegramEverySeconds: 3,
+			DigestEveryMinutes:   60,
+		},
+		Markets: defaultMarkets(),
+		Sports:  defaultSports(defaultMarkets()),
+		Books:   defaultBooks(),
+		BookCompare: BookCompareConfig{
+			Channel: NEW_BETS_CHANNEL,
+		},
+		// Value and consistency alerts are off until a config file turns them on
+		Pricing: PricingConfig{
+			Dispersion: 1,
+		},
+		Staking: StakingConfig{
+			Policy:        "kelly",
+			FlatStake:     10,
+			KellyFraction: 0.25,
+			Percent:       0.01,
+			MaxMatchPct:   0.05,
+		},
+		Bot: BotConfig{
+			LedgerFile: "bets_ledger.json",
+		},
+		Live: LiveConfig{
+			EverySeconds: 5,
+		},
+		Schedule: defaultSchedule(),
+		AlertRules: RulesConfig{
+			ReloadSeconds: 10,
+		},
+	}
+}
+
+// Load config file on top of the defaults, then apply environment overrides
+// (LOG_LEVEL, LOG_FORMAT, TELEGRAM_LOG_LEVEL, LOG_DIGEST) so Railway can tweak logging without a file
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	// A file listing markets or sports replaces the default list instead of merging into it
+	cfg.Markets = nil
+	cfg.Sports = nil
+	cfg.Books = nil
+
+	data, err := os.ReadFile(path)
+	var parseErr error
+	if err == nil {
+		if parseErr = json.Unmarshal(data, &cfg); parseErr != nil {
+			// Start over from the defaults, the lists are filled below
+			cfg = defaultConfig()
+			cfg.Markets, cfg.Sports, cfg.Books = nil, nil, nil
+		}
+	}
+
+	if len(cfg.Markets) == 0 {
+		cfg.Markets = defaultMarkets()
+	}
+	if len(cfg.Sports) == 0 {
+		cfg.Sports = defaultSports(cfg.Markets)
+	}
+	for i := range cfg.Sports {
+		if len(cfg.Sports[i].Markets) == 0 && cfg.Sports[i].ID == 5 {
+			cfg.Sports[i].Markets = append([]MarketFamily(nil), cfg.Markets...)
+		}
+		cfg.Sports[i].normalize()
+	}
+	if len(cfg.Books) == 0 {
+		cfg.Books = defaultBooks()
+	}
+	for i := range cfg.Books {
+		cfg.Books[i].normalize()
+	}
+	if cfg.BookCompare.Channel == "" {
+		cfg.BookCompare.Channel = NEW_BETS_CHANNEL
+	}
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		cfg.Log.Level = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		cfg.Log.Format = v
+	}
+	if v := os.Getenv("TELEGRAM_LOG_LEVEL"); v != "" {
+		cfg.Log.TelegramLevel = v
+	}
+	if v := os.Getenv("LOG_DIGEST"); v != "" {
+		cfg.Log.Digest, _ = strconv.ParseBool(v)
+	}
+	return cfg, parseErr
+}
+
+// Parse a level name, falling back to def when it is empty or unknown
+func parseLevel(name string, def slog.Level) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return def
+	}
+	return level
+}
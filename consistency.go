@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"sort"
+)
+
+// Two prices of the same match that contradict each other
+type inconsistency struct {
+	key    string // what was compared, stable from one cycle to the next
+	reason string
+	left   string // both prices, shown side by side
+	right  string
+}
+
+func (i inconsistency) signature() string {
+	return i.left + "|" + i.right
+}
+
+func formatOption(bet Bet, opt Option) string {
+	return fmt.Sprintf("%s : %.0f @ %.2f", bet.Type, opt.Seuil, roundCote(opt.Cote))
+}
+
+func formatOver(bet Bet) string {
+	return fmt.Sprintf("%s : + %.1f @ %.2f / - %.1f @ %.2f", bet.Type, bet.Cut, roundCote(bet.Plus), bet.Cut, roundCote(bet.Moins))
+}
+
+// Compare the ladders, Over/Under lines and match total of a match:
+//   - ladder cotes must grow with the threshold
+//   - "N ou plus" is the same event as "Over N-0.5" and must not be shorter,
+//     a harder threshold must be longer than the line, and a ladder option plus
+//     the opposite Under must not be a sure bet
+//   - the match total is at least each player's count, so at the same threshold
+//     it must be shorter, and its cut can't be below a player's cut
+func findInconsistencies(m MatchData) []inconsistency {
+	var found []inconsistency
+
+	for _, bet := range m.Bet {
+		if !bet.isLadder() {
+			continue
+		}
+		options := append([]Option(nil), bet.Options...)
+		sort.Slice(options, func(i, j int) bool { return options[i].Seuil < options[j].Seuil })
+		for i := 1; i < len(options); i++ {
+			prev, opt := options[i-1], options[i]
+			if opt.Cote < prev.Cote {
+				found = append(found, inconsistency{
+					key:    fmt.Sprintf("%s|%.0f|%.0f", bet.Type, prev.Seuil, opt.Seuil),
+					reason: "cote qui baisse quand le palier monte",
+					left:   formatOption(bet, prev),
+					right:  formatOption(bet, opt),
+				})
+			}
+		}
+
+		side := betSide(bet, m.Match)
+		for _, line := range m.Bet {
+			if !line.isOverUnder() || line.Plus == 0 || betSide(line, m.Match) != side {
+				continue
+			}
+			for _, opt := range bet.Options {
+				reason := ""
+				switch {
+				case opt.Seuil == line.Cut+0.5 && opt.Cote < line.Plus:
+					reason = "palier plus court que l'Over équivalent"
+				case opt.Seuil > line.Cut+0.5 && opt.Cote <= line.Plus:
+					reason = "palier plus difficile pas plus long que l'Over"
+				case opt.Seuil <= line.Cut+0.5 && line.Moins > 0 && 1/opt.Cote+1/line.Moins < 1:
+					reason = "palier + Under = pari sûr"
+				}
+				if reason != "" {
+					found = append(found, inconsistency{
+						key:    fmt.Sprintf("%s|%.0f|%s|%.1f", bet.Type, opt.Seuil, line.Type, line.Cut),
+						reason: reason,
+						left:   formatOption(bet, opt),
+						right:  formatOver(line),
+					})
+				}
+			}
+		}
+	}
+
+	// Match total against each player
+	for _, total := range m.Bet {
+		if betSide(total, m.Match) != 0 {
+			continue
+		}
+		for _, player := range m.Bet {
+			if betSide(player, m.Match) == 0 {
+				continue
+			}
+			switch {
+			case total.isOverUnder() && player.isOverUnder() && total.Plus > 0 && player.Plus > 0:
+				reason := ""
+				switch {
+				case total.Cut < player.Cut:
+					reason = "total du match sous la ligne d'un joueur"
+				case total.Cut == player.Cut && total.Plus > player.Plus:
+					reason = "Over du match plus long que l'Over d'un joueur"
+				}
+				if reason != "" {
+					found = append(found, inconsistency{
+						key:    fmt.Sprintf("%s|%.1f|%s|%.1f", total.Type, total.Cut, player.Type, player.Cut),
+						reason: reason,
+						left:   formatOver(total),
+						right:  formatOver(player),
+					})
+				}
+			case total.isLadder() && player.isLadder():
+				cotes := make(map[float64]Option)
+				for _, opt := range player.Options {
+					cotes[opt.Seuil] = opt
+				}
+				for _, opt := range total.Options {
+					playerOpt, ok := cotes[opt.Seuil]
+					if ok && opt.Cote > playerOpt.Cote {
+						found = append(found, inconsistency{
+							key:    fmt.Sprintf("%s|%s|%.0f", total.Type, player.Type, opt.Seuil),
+							reason: "palier du match plus long que celui d'un joueur",
+							left:   formatOption(total, opt),
+							right:  formatOption(player, playerOpt),
+						})
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+func checksConsistency(family MarketFamily) bool {
+	for _, name := range config.Pricing.Consistency {
+		if name == family.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Send one alert per inconsistency, again only when its prices change
+func checkConsistency(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	if !checksConsistency(family) {
+		return
+	}
+	for _, m := range results {
+		if preferences.muted(pricingChannel(family), m.Match) {
+			continue
+		}
+		found := findInconsistencies(m)
+		if inc, ok := totalVersusPlayers(m, config.Pricing.TotalTolerance); ok {
+			found = append(found, inc)
+		}
+		for _, inc := range found {
+			if !pricingAlerts.shouldSend(m.Match.Lien+"|"+inc.key+"|consistency", inc.signature()) {
+				continue
+			}
+
+			message := fmt.Sprintf("🧩 <b>%s</b>\nIncohérence : %s\n%s\n%s\n\n🔗 <a href=\"%s\">LIEN</a>",
+				html.EscapeString(m.Match.displayTitle()), inc.reason,
+				html.EscapeString(inc.left), html.EscapeString(inc.right), m.Match.Lien)
+
+			if outcome, err := deliverAlert(lg, pricingChannel(family), "incohérence", m.Match, message, PARSE_MODE_HTML, alertKeyboard(m.Match)); err != nil {
+				lg.Error("Failed to send consistency alert", "match", m.Match.Joueurs, "err", err)
+			} else if outcome == DELIVERY_SENT {
+				lg.Info("Consistency alert sent", "match", m.Match.Joueurs, "reason", inc.reason)
+			}
+		}
+	}
+}
+
+// Probability that the sum of two independent counts is k or more
+func probSumAtLeast(a, b countModel, k int) float64 {
+	below := 0.0
+	for s := 0; s < k; s++ {
+		for i := 0; i <= s; i++ {
+			below += a.pmf(i) * b.pmf(s-i)
+		}
+	}
+	return math.Max(0, 1-below)
+}
+
+// Combine the two players' fitted distributions into a match total and compare
+// it with the posted total line of the same scope (whole match or one set): flag
+// when the margin-free over probabilities differ by more than the tolerance (0 = off)
+func totalVersusPlayers(m MatchData, tolerance float64) (inconsistency, bool) {
+	if tolerance <= 0 {
+		return inconsistency{}, false
+	}
+
+	for _, total := range m.Bet {
+		if !total.isOverUnder() || betSide(total, m.Match) != 0 {
+			continue
+		}
+		proba := overProbability(total)
+		if proba <= 0 {
+			continue
+		}
+		scope := betScope(total)
+		player1, ok1 := fitSide(&m, 1, scope)
+		player2, ok2 := fitSide(&m, 2, scope)
+		if !ok1 || !ok2 {
+			continue
+		}
+		implied := probSumAtLeast(player1, player2, int(math.Floor(total.Cut))+1)
+		if math.Abs(implied-proba) <= tolerance || implied <= 0 || implied >= 1 {
+			continue
+		}
+		return inconsistency{
+			key:    fmt.Sprintf("%s|%.1f|joueurs", total.Type, total.Cut),
+			reason: "total du match différent de la somme des joueurs",
+			left:   fmt.Sprintf("%s (%.0f%% / %.0f%%)", formatOver(total), proba*100, (1-proba)*100),
+			right: fmt.Sprintf("Somme des joueurs : moyenne %.1f, + %.1f %.0f%% / - %.1f %.0f%% (juste %.2f / %.2f)",
+				player1.mean+player2.mean, total.Cut, implied*100, total.Cut, (1-implied)*100, 1/implied, 1/(1-implied)),
+		}, true
+	}
+	return inconsistency{}, false
+}
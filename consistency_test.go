@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// Match between competitors 1 and 2; player bets carry their competitor ID
+var consistencyMatch = Match{MatchID: "1", Joueurs: "A - B", Competitor1ID: 1, Competitor2ID: 2}
+
+func ladder(betType string, competitor int64, options ...Option) Bet {
+	return Bet{Type: betType, Template: TEMPLATE_DYNAMIC, CompetitorID: competitor, Options: options}
+}
+
+func overUnder(betType string, competitor int64, cut, plus, moins float64) Bet {
+	return Bet{Type: betType, Template: TEMPLATE_OVER_UNDER, CompetitorID: competitor, Cut: cut, Plus: plus, Moins: moins}
+}
+
+func TestFindInconsistencies(t *testing.T) {
+	tests := []struct {
+		name string
+		bets []Bet
+		want []string // reasons, in order
+	}{
+		{"consistent", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 8, Cote: 1.5}, Option{Seuil: 10, Cote: 2}),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+			overUnder("Aces", 0, 18.5, 1.85, 1.95),
+			ladder("Aces", 0, Option{Seuil: 10, Cote: 1.2}),
+			ladder("Aces de B", 2, Option{Seuil: 10, Cote: 2.5}),
+		}, nil},
+		{"ladder shorter higher up", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 9, Cote: 2}, Option{Seuil: 8, Cote: 1.5}, Option{Seuil: 10, Cote: 1.9}),
+		}, []string{"cote qui baisse quand le palier monte"}},
+		{"ladder shorter than the same Over", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 10, Cote: 1.8}),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+		}, []string{"palier plus court que l'Over équivalent"}},
+		{"harder ladder not longer than the Over", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 11, Cote: 1.9}),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+		}, []string{"palier plus difficile pas plus long que l'Over"}},
+		{"ladder and Under sure bet", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 8, Cote: 2.2}),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+		}, []string{"palier + Under = pari sûr"}},
+		{"other player's line ignored", []Bet{
+			ladder("Aces de A", 1, Option{Seuil: 10, Cote: 1.8}),
+			overUnder("Aces de B", 2, 9.5, 1.9, 1.9),
+		}, nil},
+		{"total below a player's line", []Bet{
+			overUnder("Aces", 0, 8.5, 1.9, 1.9),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+		}, []string{"total du match sous la ligne d'un joueur"}},
+		{"total Over longer than a player's", []Bet{
+			overUnder("Aces", 0, 9.5, 2.1, 1.7),
+			overUnder("Aces de A", 1, 9.5, 1.9, 1.9),
+		}, []string{"Over du match plus long que l'Over d'un joueur"}},
+		{"total ladder longer than a player's", []Bet{
+			ladder("Aces", 0, Option{Seuil: 10, Cote: 2.6}),
+			ladder("Aces de B", 2, Option{Seuil: 10, Cote: 2.5}),
+		}, []string{"palier du match plus long que celui d'un joueur"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, inc := range findInconsistencies(MatchData{Match: consistencyMatch, Bet: tt.bets}) {
+			got = append(got, inc.reason)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProbSumAtLeast(t *testing.T) {
+	// The sum of two Poisson counts is Poisson with the summed mean
+	a, b := countModel{mean: 2, dispersion: 1}, countModel{mean: 3, dispersion: 1}
+	sum := countModel{mean: 5, dispersion: 1}
+	for _, k := range []int{0, 1, 5, 9} {
+		if got, want := probSumAtLeast(a, b, k), sum.probAtLeast(k); math.Abs(got-want) > 1e-9 {
+			t.Errorf("probSumAtLeast(%d) = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func TestTotalVersusPlayers(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config.Pricing.Dispersion = 1
+
+	// Each player 50/50 over 4.5 (mean about 4.67): the sum is over 9.5 about 45% of the time
+	players := []Bet{
+		overUnder("Aces de A", 1, 4.5, 1.9, 1.9),
+		overUnder("Aces de B", 2, 4.5, 1.9, 1.9),
+	}
+	match := func(total Bet) MatchData {
+		return MatchData{Match: consistencyMatch, Bet: append([]Bet{total}, players...)}
+	}
+	tests := []struct {
+		name      string
+		data      MatchData
+		tolerance float64
+		want      bool
+	}{
+		{"total off by more than the tolerance", match(overUnder("Aces", 0, 9.5, 1.9, 1.9)), 0.02, true},
+		{"total within the tolerance", match(overUnder("Aces", 0, 9.5, 1.9, 1.9)), 0.2, false},
+		{"off", match(overUnder("Aces", 0, 9.5, 1.9, 1.9)), 0, false},
+		// Players only have whole-match lines: a set total isn't compared with them
+		{"set total", match(overUnder("1er set - Aces", 0, 9.5, 1.9, 1.9)), 0.02, false},
+		{"no player lines", MatchData{Match: consistencyMatch, Bet: []Bet{overUnder("Aces", 0, 9.5, 1.9, 1.9)}}, 0.02, false},
+	}
+	for _, tt := range tests {
+		if _, got := totalVersusPlayers(tt.data, tt.tolerance); got != tt.want {
+			t.Errorf("%s: flagged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alerts held during quiet hours and recent alert times per chat and match
+const DELIVERY_FILE = "delivery.json"
+
+// What quiet hours do to alerts
+const (
+	QUIET_SILENT = "silent" // sent without sound (disable_notification)
+	QUIET_QUEUE  = "queue"  // held, then summarized in one message when quiet hours end
+)
+
+// How alerts reach each chat: the default applies to every chat without an
+// entry of its own in Channels (keyed by chat ID), which replaces it entirely
+type DeliveryConfig struct {
+	Default  ChannelDelivery            `json:"default"`
+	Channels map[string]ChannelDelivery `json:"channels"`
+}
+
+type ChannelDelivery struct {
+	QuietStart     int    `json:"quiet_start"`      // local hour quiet hours start (same as quiet_end = none)
+	QuietEnd       int    `json:"quiet_end"`        // local hour they end
+	QuietMode      string `json:"quiet_mode"`       // silent (default) or queue
+	DedupeHours    int    `json:"dedupe_hours"`     // a match gets new bets notified once in this window (0 = NOTIFICATION_EXPIRY_DAYS)
+	RealertMinutes int    `json:"realert_minutes"`  // minimum gap between two alerts on the same match (0 = none)
+	MaxMatchAlerts int    `json:"max_match_alerts"` // alerts on the same match within the dedupe window (0 = unlimited)
+}
+
+func (c DeliveryConfig) forChat(chatID string) ChannelDelivery {
+	if policy, ok := c.Channels[chatID]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// Same hour arithmetic as the scheduler's night period
+func (d ChannelDelivery) quiet(now time.Time) bool {
+	return ScheduleConfig{NightStart: d.QuietStart, NightEnd: d.QuietEnd}.isNight(now)
+}
+
+func (d ChannelDelivery) dedupeWindow() time.Duration {
+	if d.DedupeHours > 0 {
+		return time.Duration(d.DedupeHours) * time.Hour
+	}
+	return NOTIFICATION_EXPIRY_DAYS * 24 * time.Hour
+}
+
+func (d ChannelDelivery) capped() bool {
+	return d.RealertMinutes > 0 || d.MaxMatchAlerts > 0
+}
+
+// An alert held for the quiet hours summary
+type queuedAlert struct {
+	Kind       string    `json:"kind"`
+	Joueurs    string    `json:"joueurs"`
+	Lien       string    `json:"lien"`
+	MatchStart int64     `json:"matchStart,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+type deliveryRecord struct {
+	Queued  map[string][]queuedAlert `json:"queued"`  // chat -> alerts held during quiet hours
+	Alerted map[string][]time.Time   `json:"alerted"` // chat|match -> recent alerts, for the re-alert cap
+}
+
+type deliveryState struct {
+	mu   sync.Mutex
+	path string
+	deliveryRecord
+}
+
+var delivery = &deliveryState{
+	path: DELIVERY_FILE,
+	deliveryRecord: deliveryRecord{
+		Queued:  make(map[string][]queuedAlert),
+		Alerted: make(map[string][]time.Time),
+	},
+}
+
+func (s *deliveryState) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &s.deliveryRecord); err != nil {
+		return err
+	}
+	if s.Queued == nil {
+		s.Queued = make(map[string][]queuedAlert)
+	}
+	if s.Alerted == nil {
+		s.Alerted = make(map[string][]time.Time)
+	}
+	return nil
+}
+
+// Callers hold the lock. Alert times past their chat's dedupe window are dropped.
+func (s *deliveryState) save(now time.Time) error {
+	for key, times := range s.Alerted {
+		chatID, _, _ := strings.Cut(key, "|")
+		if times = recentTimes(times, now, config.Delivery.forChat(chatID).dedupeWindow()); len(times) > 0 {
+			s.Alerted[key] = times
+		} else {
+			delete(s.Alerted, key)
+		}
+	}
+	data, err := json.MarshalIndent(s.deliveryRecord, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Callers hold the lock
+func (s *deliveryState) saveLogged(now time.Time, lg *slog.Logger) {
+	if err := s.save(now); err != nil {
+		lg.Error("Error saving delivery state", "file", s.path, "err", err)
+	}
+}
+
+func recentTimes(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	var kept []time.Time
+	for _, at := range times {
+		if now.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+	return kept
+}
+
+// Matches are counted by matchId, or by link when the book has none
+func deliveryMatchKey(m Match) string {
+	if m.MatchID != "" {
+		return m.MatchID
+	}
+	return m.Lien
+}
+
+// What became of an alert given to deliverAlert
+type deliveryOutcome int
+
+const (
+	DELIVERY_SENT      deliveryOutcome = iota // or tried, when an error comes with it
+	DELIVERY_QUEUED                           // held for the quiet hours summary
+	DELIVERY_HELD_BACK                        // dropped by the re-alert cap
+)
+
+// Send an alert on a match to a chat under the chat's delivery policy: it may
+// be held back by the re-alert cap or queued for the quiet hours summary, and
+// during silent quiet hours it is sent without sound. Only alerts sent or
+// queued count towards the cap.
+func deliverAlert(lg *slog.Logger, chatID, kind string, m Match, message, parseMode string, keyboard [][]inlineButton) (deliveryOutcome, error) {
+	policy := config.Delivery.forChat(chatID)
+	now := time.Now()
+	quiet := policy.quiet(now)
+	queue := quiet && policy.QuietMode == QUIET_QUEUE
+	key := chatID + "|" + deliveryMatchKey(m)
+
+	s := delivery
+	s.mu.Lock()
+	if policy.capped() {
+		recent := recentTimes(s.Alerted[key], now, policy.dedupeWindow())
+		tooSoon := policy.RealertMinutes > 0 && len(recent) > 0 &&
+			now.Sub(recent[len(recent)-1]) < time.Duration(policy.RealertMinutes)*time.Minute
+		tooMany := policy.MaxMatchAlerts > 0 && len(recent) >= policy.MaxMatchAlerts
+		if tooSoon || tooMany {
+			s.mu.Unlock()
+			lg.Info("Alert held back, match alerted too often", "chat", chatID, "match", m.Joueurs, "kind", kind, "recent", len(recent))
+			return DELIVERY_HELD_BACK, nil
+		}
+	}
+	if !queue {
+		s.mu.Unlock()
+		if err := sendTelegramAlert(chatID, message, parseMode, keyboard, quiet); err != nil {
+			return DELIVERY_SENT, err
+		}
+		if policy.capped() {
+			s.mu.Lock()
+			s.Alerted[key] = append(s.Alerted[key], now)
+			s.saveLogged(now, lg)
+			s.mu.Unlock()
+		}
+		return DELIVERY_SENT, nil
+	}
+
+	s.Queued[chatID] = append(s.Queued[chatID], queuedAlert{
+		Kind:       kind,
+		Joueurs:    m.displayTitle(),
+		Lien:       m.Lien,
+		MatchStart: m.MatchStart,
+		At:         now,
+	})
+	if policy.capped() {
+		s.Alerted[key] = append(s.Alerted[key], now)
+	}
+	s.saveLogged(now, lg)
+	s.mu.Unlock()
+	lg.Info("Alert queued for the quiet hours summary", "chat", chatID, "match", m.Joueurs, "kind", kind)
+	return DELIVERY_QUEUED, nil
+}
+
+// Post the summary of every chat whose quiet hours are over
+func (s *deliveryState) flush(now time.Time, lg *slog.Logger) {
+	s.mu.Lock()
+	due := make(map[string][]queuedAlert)
+	for chatID, alerts := range s.Queued {
+		if len(alerts) > 0 && !config.Delivery.forChat(chatID).quiet(now) {
+			due[chatID] = alerts
+			delete(s.Queued, chatID)
+		}
+	}
+	if len(due) > 0 {
+		s.saveLogged(now, lg)
+	}
+	s.mu.Unlock()
+
+	for chatID, alerts := range due {
+		if err := sendQuietSummary(chatID, alerts, now); err != nil {
+			lg.Error("Failed to send quiet hours summary, kept for the next attempt", "chat", chatID, "err", err)
+			s.mu.Lock()
+			s.Queued[chatID] = append(alerts, s.Queued[chatID]...)
+			s.saveLogged(now, lg)
+			s.mu.Unlock()
+			continue
+		}
+		lg.Info("Quiet hours summary sent", "chat", chatID, "alerts", len(alerts))
+	}
+}
+
+func sendQuietSummary(chatID string, alerts []queuedAlert, now time.Time) error {
+	for _, message := range quietSummary(alerts, now) {
+		if err := sendTelegramMessage(chatID, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// One line per match with the kinds of alerts it had, in the order they came;
+// matches that started meanwhile are only counted
+func quietSummary(alerts []queuedAlert, now time.Time) []string {
+	type matchAlerts struct {
+		joueurs, lien string
+		kinds         []string
+		counts        map[string]int
+	}
+	var order []*matchAlerts
+	byMatch := make(map[string]*matchAlerts)
+	started := 0
+	for _, alert := range alerts {
+		if alert.MatchStart > 0 && alert.MatchStart <= now.Unix() {
+			started++
+			continue
+		}
+		key := alert.Lien + "|" + alert.Joueurs
+		ma, ok := byMatch[key]
+		if !ok {
+			ma = &matchAlerts{joueurs: alert.Joueurs, lien: alert.Lien, counts: make(map[string]int)}
+			byMatch[key] = ma
+			order = append(order, ma)
+		}
+		if ma.counts[alert.Kind] == 0 {
+			ma.kinds = append(ma.kinds, alert.Kind)
+		}
+		ma.counts[alert.Kind]++
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	lines := []string{fmt.Sprintf("🌙 <b>Heures calmes</b> : %d alerte(s) sur %d match(s)\n", len(alerts)-started, len(order))}
+	for _, ma := range order {
+		var kinds []string
+		for _, kind := range ma.kinds {
+			if n := ma.counts[kind]; n > 1 {
+				kinds = append(kinds, fmt.Sprintf("%s ×%d", html.EscapeString(kind), n))
+			} else {
+				kinds = append(kinds, html.EscapeString(kind))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("• <b>%s</b> : %s · 🔗 <a href=\"%s\">LIEN</a>",
+			html.EscapeString(ma.joueurs), strings.Join(kinds, ", "), ma.lien))
+	}
+	if started > 0 {
+		lines = append(lines, fmt.Sprintf("\n%d alerte(s) sur des matchs déjà commencés", started))
+	}
+	return batchLines(lines, TELEGRAM_MAX_MESSAGE)
+}
+
+// Check every minute for quiet hours that ended with alerts waiting
+func runQuietSummaries(lg *slog.Logger) {
+	for {
+		time.Sleep(time.Minute)
+		delivery.flush(time.Now(), lg)
+	}
+}
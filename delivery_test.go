@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliverAlert(t *testing.T) {
+	t.Chdir(t.TempDir())
+	lg := slog.New(slog.DiscardHandler)
+	savedConfig, savedDelivery := config, delivery
+	t.Cleanup(func() { config, delivery = savedConfig, savedDelivery })
+
+	// Quiet hours around the current hour, whatever time the test runs
+	hour := time.Now().Hour()
+	queue := ChannelDelivery{QuietStart: (hour + 23) % 24, QuietEnd: (hour + 2) % 24, QuietMode: QUIET_QUEUE}
+	queueCapped := queue
+	queueCapped.MaxMatchAlerts = 2
+	config.Delivery = DeliveryConfig{Channels: map[string]ChannelDelivery{
+		"queue":   queue,
+		"capped":  queueCapped,
+		"max":     {MaxMatchAlerts: 1},
+		"realert": {RealertMinutes: 30},
+	}}
+	m := Match{MatchID: "42", Joueurs: "A - B", Lien: "/match/42"}
+	ago := func(d time.Duration) []time.Time { return []time.Time{time.Now().Add(-d)} }
+
+	tests := []struct {
+		name    string
+		chat    string
+		alerted []time.Time // earlier alerts on the match
+		want    deliveryOutcome
+		queued  int
+		counted int // alerts on the match recorded afterwards
+	}{
+		{"queued during quiet hours", "queue", nil, DELIVERY_QUEUED, 1, 0},
+		{"queued and counted", "capped", ago(time.Hour), DELIVERY_QUEUED, 1, 2},
+		{"queue cap reached", "capped", append(ago(2*time.Hour), ago(time.Hour)...), DELIVERY_HELD_BACK, 0, 2},
+		{"max alerts reached", "max", ago(time.Hour), DELIVERY_HELD_BACK, 0, 1},
+		{"re-alert too soon", "realert", ago(10 * time.Minute), DELIVERY_HELD_BACK, 0, 1},
+		// Past the dedupe window, an alert no longer counts
+		{"old alert forgotten", "capped", append(ago(NOTIFICATION_EXPIRY_DAYS*24*time.Hour+time.Hour), ago(time.Hour)...), DELIVERY_QUEUED, 1, 2},
+	}
+	for _, tt := range tests {
+		delivery = &deliveryState{path: DELIVERY_FILE, deliveryRecord: deliveryRecord{
+			Queued:  make(map[string][]queuedAlert),
+			Alerted: map[string][]time.Time{tt.chat + "|42": tt.alerted},
+		}}
+		got, err := deliverAlert(lg, tt.chat, "value", m, "message", "HTML", nil)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: outcome %v (%v), want %v", tt.name, got, err, tt.want)
+		}
+		if n := len(delivery.Queued[tt.chat]); n != tt.queued {
+			t.Errorf("%s: %d alert(s) queued, want %d", tt.name, n, tt.queued)
+		}
+		if n := len(recentTimes(delivery.Alerted[tt.chat+"|42"], time.Now(), NOTIFICATION_EXPIRY_DAYS*24*time.Hour)); n != tt.counted {
+			t.Errorf("%s: %d recent alert(s), want %d", tt.name, n, tt.counted)
+		}
+	}
+}
+
+func TestQuietSummary(t *testing.T) {
+	now := time.Date(2026, 5, 10, 7, 0, 0, 0, time.Local)
+	later, earlier := now.Add(3*time.Hour).Unix(), now.Add(-time.Hour).Unix()
+	alert := func(kind, joueurs string, start int64) queuedAlert {
+		return queuedAlert{Kind: kind, Joueurs: joueurs, Lien: "/match/" + joueurs, MatchStart: start}
+	}
+
+	got := quietSummary([]queuedAlert{
+		alert("value", "A - B", later),
+		alert("cross", "C - D", 0),
+		alert("value", "A - B", later),
+		alert("steam", "E - F", earlier),
+		alert("pricing", "A - B", later),
+		alert("value", "E - F", earlier),
+	}, now)
+	if len(got) != 1 {
+		t.Fatalf("%d messages, want 1: %q", len(got), got)
+	}
+	want := []string{
+		"🌙 <b>Heures calmes</b> : 4 alerte(s) sur 2 match(s)",
+		"• <b>A - B</b> : value ×2, pricing · 🔗 <a href=\"/match/A - B\">LIEN</a>",
+		"• <b>C - D</b> : cross · 🔗 <a href=\"/match/C - D\">LIEN</a>",
+		"2 alerte(s) sur des matchs déjà commencés",
+	}
+	for _, line := range want {
+		if !strings.Contains(got[0], line) {
+			t.Errorf("summary misses %q:\n%s", line, got[0])
+		}
+	}
+	if strings.Contains(got[0], "E - F") {
+		t.Errorf("summary lists a started match:\n%s", got[0])
+	}
+
+	if got := quietSummary([]queuedAlert{alert("value", "E - F", earlier)}, now); got != nil {
+		t.Errorf("only started matches: %q, want nothing", got)
+	}
+}
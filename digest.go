@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counters for the periodic LOG_CHANNEL summary. They are always updated; the
+// summary is only posted (and routine logs only suppressed) in digest mode.
+type digestStats struct {
+	mu            sync.Mutex
+	since         time.Time
+	cycles        int
+	okCycles      int
+	proxySwitches int
+	notifications int
+	matches       map[string]bool
+	bets          map[string]bool
+	errors        map[errorKey]int // occurrences in the current window
+}
+
+// Errors are grouped by level, message and kind of error: the err text with its
+// numbers (ports, IDs, counts) masked, so errors that differ only there are counted together
+type errorKey struct {
+	level, msg, err string
+}
+
+var errorNumberRegexp = regexp.MustCompile(`\d+`)
+
+func errorKind(errText string) string {
+	return errorNumberRegexp.ReplaceAllString(errText, "#")
+}
+
+var digest = newDigestStats()
+
+func newDigestStats() *digestStats {
+	d := &digestStats{}
+	d.reset()
+	return d
+}
+
+func (d *digestStats) reset() {
+	d.since = time.Now()
+	d.cycles = 0
+	d.okCycles = 0
+	d.proxySwitches = 0
+	d.notifications = 0
+	d.matches = make(map[string]bool)
+	d.bets = make(map[string]bool)
+	d.errors = make(map[errorKey]int)
+}
+
+func (d *digestStats) cycleStarted() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cycles++
+}
+
+func (d *digestStats) proxySwitched() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.proxySwitches++
+}
+
+func (d *digestStats) notificationSent() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifications++
+}
+
+// Record a successful cycle and the distinct matches/bets it saw
+func (d *digestStats) cycleSucceeded(results []MatchData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.okCycles++
+	for _, m := range results {
+		d.matches[m.Match.Lien] = true
+		for _, bet := range m.Bet {
+			d.bets[m.Match.Lien+"|"+generateBetKey(bet)] = true
+		}
+	}
+}
+
+// Returns true the first time a message is seen with this kind of error in the
+// current window, so the error is posted immediately; later duplicates are only
+// counted for the summary
+func (d *digestStats) firstOccurrence(level slog.Level, message, errText string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := errorKey{level: level.String(), msg: message, err: errorKind(errText)}
+	d.errors[key]++
+	return d.errors[key] == 1
+}
+
+// Build the summary message and start a new window
+func (d *digestStats) report() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📊 <b>Résumé %s → %s</b>\n\n", d.since.Format("15:04"), time.Now().Format("15:04")))
+
+	rate := 0.0
+	if d.cycles > 0 {
+		rate = float64(d.okCycles) / float64(d.cycles) * 100
+	}
+	message.WriteString(fmt.Sprintf("🔄 Cycles : %d (%.0f%% OK)\n", d.cycles, rate))
+	message.WriteString(fmt.Sprintf("🔀 Changements de proxy : %d\n", d.proxySwitches))
+	message.WriteString(fmt.Sprintf("🎾 Matchs vus : %d\n", len(d.matches)))
+	message.WriteString(fmt.Sprintf("🎯 Paris vus : %d\n", len(d.bets)))
+	message.WriteString(fmt.Sprintf("📨 Notifications envoyées : %d\n", d.notifications))
+
+	if len(d.errors) > 0 {
+		keys := make([]errorKey, 0, len(d.errors))
+		for key := range d.errors {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return d.errors[keys[i]] > d.errors[keys[j]] })
+
+		// Keep the summary short - only the most frequent errors
+		if len(keys) > 10 {
+			keys = keys[:10]
+		}
+
+		message.WriteString("\n<b>Erreurs :</b>\n")
+		for _, key := range keys {
+			line := key.msg
+			if key.err != "" {
+				line += " : " + key.err
+			}
+			message.WriteString(fmt.Sprintf("%s ×%d\n", html.EscapeString(line), d.errors[key]))
+		}
+	}
+
+	d.reset()
+	return message.String()
+}
+
+// Post the summary every interval until the process exits
+func runDigest(chatID string, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := sendTelegramMessage(chatID, digest.report()); err != nil {
+			slog.Warn("Failed to send digest", "err", err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Count distribution fitted to an Over/Under line: Poisson, or negative binomial
+// when the variance is larger than the mean (dispersion = variance / mean > 1)
+type countModel struct {
+	mean       float64
+	dispersion float64
+}
+
+// Probability of exactly k events
+func (c countModel) pmf(k int) float64 {
+	if c.dispersion <= 1 {
+		return math.Exp(-c.mean + float64(k)*math.Log(c.mean) - lgamma(float64(k)+1))
+	}
+	r := c.mean / (c.dispersion - 1)
+	p := r / (r + c.mean)
+	return math.Exp(lgamma(float64(k)+r) - lgamma(r) - lgamma(float64(k)+1) + r*math.Log(p) + float64(k)*math.Log(1-p))
+}
+
+// Probability of k events or more, i.e. of the ladder option "k ou plus"
+func (c countModel) probAtLeast(k int) float64 {
+	below := 0.0
+	for i := 0; i < k; i++ {
+		below += c.pmf(i)
+	}
+	return math.Max(0, 1-below)
+}
+
+func lgamma(x float64) float64 {
+	v, _ := math.Lgamma(x)
+	return v
+}
+
+// Find the mean that gives the margin-free over probability of a line
+// (over 9.5 = 10 or more). The probability grows with the mean, so bisect.
+func fitCountModel(cut, probaPlus, dispersion float64) (countModel, bool) {
+	if probaPlus <= 0 || probaPlus >= 1 {
+		return countModel{}, false
+	}
+	k := int(math.Floor(cut)) + 1
+	lo, hi := 0.01, 200.0
+	model := countModel{dispersion: dispersion}
+	for i := 0; i < 60; i++ {
+		model.mean = (lo + hi) / 2
+		if model.probAtLeast(k) < probaPlus {
+			lo = model.mean
+		} else {
+			hi = model.mean
+		}
+	}
+	return model, true
+}
+
+// Margin-free over probability of an Over/Under line from its raw cotes, not
+// the rounded ProbaPlus kept for display
+func overProbability(bet Bet) float64 {
+	if bet.Plus <= 0 || bet.Moins <= 0 {
+		return 0
+	}
+	return (1 / bet.Plus) / (1/bet.Plus + 1/bet.Moins)
+}
+
+// "1er set - Nombre de jeux", "2e set", "Set 3"...
+var setScopeRegexp = regexp.MustCompile(`(?i)\b(\d+)\s*(?:er|re|e|ème|eme|\.?º)?\s+set\b|\bset\s+(\d+)\b`)
+
+// Part of the match a bet counts: "set N" for a set market, "" for the whole match
+func betScope(bet Bet) string {
+	s := setScopeRegexp.FindStringSubmatch(bet.Type)
+	switch {
+	case s == nil:
+		return ""
+	case s[1] != "":
+		return "set " + s[1]
+	}
+	return "set " + s[2]
+}
+
+// Model for a player (side 1 or 2) or the match (0) over one scope (see betScope),
+// fitted to their most balanced Over/Under line of that scope - the one whose
+// margin-free probabilities are closest to 50/50
+func fitSide(m *MatchData, side int, scope string) (countModel, bool) {
+	best, bestProba := Bet{}, 0.0
+	for _, bet := range m.Bet {
+		if !bet.isOverUnder() || betSide(bet, m.Match) != side || betScope(bet) != scope {
+			continue
+		}
+		proba := overProbability(bet)
+		if proba <= 0 {
+			continue
+		}
+		if bestProba == 0 || math.Abs(proba-0.5) < math.Abs(bestProba-0.5) {
+			best, bestProba = bet, proba
+		}
+	}
+	if bestProba == 0 {
+		return countModel{}, false
+	}
+	return fitCountModel(best.Cut, bestProba, config.Pricing.Dispersion)
+}
+
+// Fair probability, fair cote and expected value of every ladder option whose
+// player (or the match, for total ladders) also has an Over/Under line.
+// Needs the margins from annotateMargins.
+func annotateFairPrices(m *MatchData) {
+	for i := range m.Bet {
+		bet := &m.Bet[i]
+		if !bet.isLadder() {
+			continue
+		}
+		model, ok := fitSide(m, betSide(*bet, m.Match), betScope(*bet))
+		if !ok {
+			continue
+		}
+		for j := range bet.Options {
+			opt := &bet.Options[j]
+			proba := model.probAtLeast(int(math.Ceil(opt.Seuil)))
+			if proba <= 0 {
+				continue
+			}
+			opt.Proba = round4(proba)
+			opt.CoteJuste = math.Round(100/proba) / 100
+			opt.Value = round4(opt.Cote*proba - 1) // raw cote, rounding would swamp small edges
+		}
+	}
+}
+
+// " · juste 2.25" when the fair cote is known
+func formatFair(opt Option) string {
+	if opt.CoteJuste == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" · juste %.2f", opt.CoteJuste)
+}
+
+// Alert on ladder options priced above their fair cote by at least the configured edge
+func checkValue(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	minEdge := config.Pricing.ValueMinEdge
+	if minEdge <= 0 {
+		return
+	}
+	for _, m := range results {
+		if preferences.muted(pricingChannel(family), m.Match) {
+			continue
+		}
+		for _, bet := range m.Bet {
+			var flagged []Option
+			for _, opt := range bet.Options {
+				if opt.CoteJuste != 0 && opt.Value >= minEdge {
+					flagged = append(flagged, opt)
+				}
+			}
+			stakes := config.Staking.suggest(flagged)
+			var lines []string
+			for i, opt := range flagged {
+				lines = append(lines, fmt.Sprintf("%.0f @ %.2f%s (+%.1f%%)%s", opt.Seuil, roundCote(opt.Cote), formatFair(opt), opt.Value*100, formatStake(stakes[i])))
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			key := m.Match.Lien + "|" + generateLineKey(bet) + "|value"
+			if !pricingAlerts.shouldSend(key, strings.Join(lines, ",")) {
+				continue
+			}
+
+			var message strings.Builder
+			message.WriteString(fmt.Sprintf("💎 <b>%s</b>\n", html.EscapeString(m.Match.displayTitle())))
+			message.WriteString(fmt.Sprintf("<b>%s</b> - value :\n", html.EscapeString(bet.Type)))
+			message.WriteString(strings.Join(lines, "\n"))
+			message.WriteString(fmt.Sprintf("\n\n🔗 <a href=\"%s\">LIEN</a>", m.Match.Lien))
+
+			// One button per option to record it in the ledger at the suggested stake
+			var keyboard [][]inlineButton
+			for i, opt := range flagged {
+				if stakes[i] > 0 && m.Match.MatchID != "" {
+					keyboard = append(keyboard, []inlineButton{{
+						Text:         fmt.Sprintf("💰 %.0f @ %.2f (%.0f€)", opt.Seuil, roundCote(opt.Cote), stakes[i]),
+						CallbackData: betCallbackData(m.Match.MatchID, bet, opt, stakes[i]),
+					}})
+				}
+			}
+
+			keyboard = append(keyboard, alertKeyboard(m.Match)...)
+
+			if outcome, err := deliverAlert(lg, pricingChannel(family), "value", m.Match, message.String(), PARSE_MODE_HTML, keyboard); err != nil {
+				lg.Error("Failed to send value alert", "match", m.Match.Joueurs, "err", err)
+			} else if outcome == DELIVERY_SENT {
+				lg.Info("Value alert sent", "match", m.Match.Joueurs, "bet", bet.Type, "options", len(lines))
+				alerted := bet
+				alerted.Options = flagged
+				clv.track(family, m.Match, []Bet{alerted}, "value")
+			}
+		}
+	}
+}
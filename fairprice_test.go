@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCountModelProbAtLeast(t *testing.T) {
+	tests := []struct {
+		name  string
+		model countModel
+		k     int
+		want  float64
+	}{
+		{"poisson 0 or more", countModel{mean: 3, dispersion: 1}, 0, 1},
+		{"poisson 1 or more", countModel{mean: 2, dispersion: 1}, 1, 1 - math.Exp(-2)},
+		{"poisson mean 10", countModel{mean: 10, dispersion: 1}, 10, 0.5420702855281477},
+		{"dispersion below 1 is poisson", countModel{mean: 10, dispersion: 0.8}, 10, 0.5420702855281477},
+		// r = 2, p = 0.5: P(0) = p^r
+		{"negative binomial 1 or more", countModel{mean: 2, dispersion: 2}, 1, 0.75},
+		{"negative binomial mean 8", countModel{mean: 8, dispersion: 2}, 8, 0.5},
+		{"negative binomial mean 12", countModel{mean: 12, dispersion: 1.5}, 12, 0.5157355327865019},
+	}
+	for _, tt := range tests {
+		if got := tt.model.probAtLeast(tt.k); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: probAtLeast(%d) = %v, want %v", tt.name, tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestFitCountModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		cut        float64
+		probaPlus  float64
+		dispersion float64
+		wantMean   float64
+	}{
+		{"poisson over 9.5", 9.5, 0.5420702855281477, 1, 10},
+		{"poisson over 6.5", 6.5, 0.4734763774820001, 1, 6.5},
+		{"negative binomial over 7.5", 7.5, 0.5, 2, 8},
+		{"negative binomial over 11.5", 11.5, 0.5157355327865019, 1.5, 12},
+	}
+	for _, tt := range tests {
+		model, ok := fitCountModel(tt.cut, tt.probaPlus, tt.dispersion)
+		if !ok {
+			t.Errorf("%s: no model", tt.name)
+			continue
+		}
+		if math.Abs(model.mean-tt.wantMean) > 1e-6 {
+			t.Errorf("%s: mean = %v, want %v", tt.name, model.mean, tt.wantMean)
+		}
+		if model.dispersion != tt.dispersion {
+			t.Errorf("%s: dispersion = %v, want %v", tt.name, model.dispersion, tt.dispersion)
+		}
+	}
+
+	for _, proba := range []float64{0, 1, -0.1, 1.2} {
+		if _, ok := fitCountModel(9.5, proba, 1); ok {
+			t.Errorf("fitCountModel(9.5, %v) fitted a model, want none", proba)
+		}
+	}
+}
@@ -3,13 +3,13 @@ module winamax-scraper
 go 1.24.0
 
 require (
+	github.com/RealAlexandreAI/json-repair v0.0.14
 	github.com/bogdanfinn/fhttp v0.5.36
 	github.com/bogdanfinn/tls-client v1.9.1
 	github.com/google/uuid v1.6.0
 )
 
 require (
-	github.com/RealAlexandreAI/json-repair v0.0.14 // indirect
 	github.com/andybalholm/brotli v1.1.1 // indirect
 	github.com/bogdanfinn/utls v1.6.5 // indirect
 	github.com/cloudflare/circl v1.5.0 // indirect
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notification history file to track sent notifications
+const NOTIFICATION_HISTORY_FILE = "sent_notifications.json"
+const LEGACY_NOTIFICATION_HISTORY_FILE = "sent_notifications.txt" // timestamp|joueurs|matchLink, before matchId keys
+const NOTIFICATION_EXPIRY_DAYS = 7                                // Entries older than this are removed, unless the channel sets its own dedupe_hours
+
+// A match we already sent new bets for. Matches are recognised by matchId (and
+// competitor IDs when both sides know them); entries migrated from the text
+// history only have names and fall back to a fuzzy name comparison.
+type notificationEntry struct {
+	SentAt        time.Time `json:"sentAt"`
+	MatchID       string    `json:"matchId,omitempty"`
+	Competitor1ID int64     `json:"competitor1Id,omitempty"`
+	Competitor2ID int64     `json:"competitor2Id,omitempty"`
+	Joueurs       string    `json:"joueurs"`
+	Lien          string    `json:"lien,omitempty"`
+}
+
+func (e notificationEntry) expired(now time.Time, window time.Duration) bool {
+	return now.Sub(e.SentAt) > window
+}
+
+// Same competitors in any order; unknown IDs don't disagree
+func sameCompetitors(a1, a2, b1, b2 int64) bool {
+	if a1 == 0 || a2 == 0 || b1 == 0 || b2 == 0 {
+		return true
+	}
+	return (a1 == b1 && a2 == b2) || (a1 == b2 && a2 == b1)
+}
+
+func (e notificationEntry) matches(m Match) bool {
+	if e.MatchID != "" && m.MatchID != "" {
+		return e.MatchID == m.MatchID && sameCompetitors(e.Competitor1ID, e.Competitor2ID, m.Competitor1ID, m.Competitor2ID)
+	}
+	return sameMatchNames(e.Joueurs, m.Joueurs)
+}
+
+// Load the history, migrating the text file of the same family the first time
+func loadNotificationHistory(family MarketFamily) []notificationEntry {
+	path := family.historyFile()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return migrateNotificationHistory(family)
+	}
+	if err != nil {
+		slog.Error("Error reading notification history", "file", path, "err", err)
+		return nil
+	}
+	var history []notificationEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		slog.Error("Error parsing notification history", "file", path, "err", err)
+		return nil
+	}
+	return history
+}
+
+// Convert timestamp|joueurs|matchLink lines, taking the matchId from the link
+// when there is one, then set the text file aside so it's only done once
+func migrateNotificationHistory(family MarketFamily) []notificationEntry {
+	legacy := family.legacyHistoryFile()
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return nil
+	}
+
+	var history []notificationEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "|", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		sentAt, err := time.Parse(time.RFC3339, parts[0])
+		if err != nil {
+			continue
+		}
+		entry := notificationEntry{SentAt: sentAt, Joueurs: parts[1]}
+		if len(parts) == 3 && parts[2] != "unknown" {
+			entry.Lien = parts[2]
+			if found := matchIDRegexp.FindStringSubmatch(parts[2]); found != nil {
+				entry.MatchID = found[1]
+			}
+		}
+		history = append(history, entry)
+	}
+
+	saveNotificationHistory(family, history)
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		slog.Warn("Failed to set migrated notification history aside", "file", legacy, "err", err)
+	}
+	slog.Info("Notification history migrated", "from", legacy, "to", family.historyFile(), "entries", len(history))
+	return history
+}
+
+// Save the history, dropping entries older than the channel's dedupe window
+func saveNotificationHistory(family MarketFamily, history []notificationEntry) {
+	now := time.Now()
+	window := config.Delivery.forChat(family.Channel).dedupeWindow()
+	kept := []notificationEntry{}
+	for _, entry := range history {
+		if !entry.expired(now, window) {
+			kept = append(kept, entry)
+		}
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding notification history", "err", err)
+		return
+	}
+	if err := os.WriteFile(family.historyFile(), data, 0644); err != nil {
+		slog.Error("Error writing notification history", "file", family.historyFile(), "err", err)
+	}
+}
+
+// Check if notification was already sent for this match
+func wasNotificationSent(family MarketFamily, m Match) bool {
+	now := time.Now()
+	window := config.Delivery.forChat(family.Channel).dedupeWindow()
+	for _, entry := range loadNotificationHistory(family) {
+		if !entry.expired(now, window) && entry.matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mark notification as sent for this match
+func markNotificationSent(family MarketFamily, m Match) {
+	history := loadNotificationHistory(family)
+	history = append(history, notificationEntry{
+		SentAt:        time.Now(),
+		MatchID:       m.MatchID,
+		Competitor1ID: m.Competitor1ID,
+		Competitor2ID: m.Competitor2ID,
+		Joueurs:       m.Joueurs,
+		Lien:          m.Lien,
+	})
+	saveNotificationHistory(family, history)
+}
+
+// Two titles name the same match when each side has the same last names,
+// whatever the order of the sides, accents, case or initials
+func sameMatchNames(a, b string) bool {
+	keyA, keyB := matchNamesKey(a), matchNamesKey(b)
+	return keyA != "" && keyA == keyB
+}
+
+func matchNamesKey(title string) string {
+	var sides []string
+	for _, side := range strings.Split(title, " - ") {
+		var names []string
+		for _, player := range pairMembers(side) {
+			if name := lastName(player); name != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		sides = append(sides, strings.Join(names, "+"))
+	}
+	sort.Strings(sides)
+	return strings.Join(sides, "|")
+}
+
+// Last word of a player name without the initials, folded for comparison
+func lastName(player string) string {
+	fields := strings.Fields(strings.ReplaceAll(foldName(player), ".", ". "))
+	for i := len(fields) - 1; i >= 0; i-- {
+		if !strings.HasSuffix(fields[i], ".") {
+			return fields[i]
+		}
+	}
+	return ""
+}
+
+var accentReplacer = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ą", "a", "ă", "a",
+	"ç", "c", "ć", "c", "č", "c",
+	"ď", "d", "đ", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ę", "e", "ě", "e",
+	"ğ", "g",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ı", "i",
+	"ł", "l",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ő", "o",
+	"ř", "r",
+	"ś", "s", "š", "s", "ş", "s", "ș", "s",
+	"ť", "t", "ț", "t",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u", "ű", "u",
+	"ý", "y", "ÿ", "y",
+	"ź", "z", "ż", "z", "ž", "z",
+	"-", " ", "'", "",
+)
+
+// Lower case without accents, hyphens as spaces
+func foldName(name string) string {
+	return strings.Join(strings.Fields(accentReplacer.Replace(strings.ToLower(name))), " ")
+}
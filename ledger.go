@@ -0,0 +1,533 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bet statuses
+const (
+	BET_OPEN = "ouvert"
+	BET_WON  = "gagné"
+	BET_LOST = "perdu"
+	BET_VOID = "remboursé"
+)
+
+// A bet we actually placed, recorded from the bot
+type LedgerBet struct {
+	ID        int        `json:"id"`
+	PlacedAt  time.Time  `json:"placedAt"`
+	PlacedBy  string     `json:"placedBy,omitempty"`
+	MatchID   string     `json:"matchId"`
+	Joueurs   string     `json:"joueurs"`
+	Family    string     `json:"family,omitempty"` // market family, e.g. aces or jeux
+	Type      string     `json:"type"`
+	BetTypeID int        `json:"betTypeId,omitempty"`
+	Side      int        `json:"side"`   // 1 or 2 for a player, 0 for the match
+	Market    string     `json:"market"` // paliers, plus or moins
+	Seuil     float64    `json:"seuil"`
+	Cote      float64    `json:"cote"`
+	Mise      float64    `json:"mise"`
+	Statut    string     `json:"statut"`
+	SettledAt *time.Time `json:"settledAt,omitempty"`
+}
+
+// What the bet returns once settled (stake included), 0 while open
+func (b LedgerBet) payout() float64 {
+	switch b.Statut {
+	case BET_WON:
+		return b.Mise * b.Cote
+	case BET_VOID:
+		return b.Mise
+	}
+	return 0
+}
+
+// Player the bet is on (a single player or pair in doubles), or "Match" for totals
+func (b LedgerBet) player() string {
+	if name := sidePlayer(b.Type, b.Joueurs, b.Side); name != "" {
+		return name
+	}
+	return "Match"
+}
+
+func (b LedgerBet) marketLabel() string {
+	scope := "joueur"
+	if b.Side == 0 {
+		scope = "match"
+	}
+	return b.Market + " " + scope
+}
+
+func (b LedgerBet) describe() string {
+	line := fmt.Sprintf("%.0f @ %.2f", b.Seuil, b.Cote)
+	switch b.Market {
+	case "plus":
+		line = fmt.Sprintf("+ %.1f @ %.2f", b.Seuil, b.Cote)
+	case "moins":
+		line = fmt.Sprintf("- %.1f @ %.2f", b.Seuil, b.Cote)
+	}
+	return fmt.Sprintf("#%d %s - %s : %s, %.2f€", b.ID, html.EscapeString(b.Joueurs), html.EscapeString(b.Type), line, b.Mise)
+}
+
+// Bets kept in a JSON file, rewritten on every change
+type betLedger struct {
+	mu   sync.Mutex
+	path string
+	bets []LedgerBet
+}
+
+var ledger = &betLedger{}
+
+func (l *betLedger) load(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		l.path = path
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Keep path empty on error so a broken file isn't overwritten
+	if err := json.Unmarshal(data, &l.bets); err != nil {
+		return err
+	}
+	l.path = path
+	return nil
+}
+
+func (l *betLedger) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.bets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, data, 0644)
+}
+
+func (l *betLedger) add(bet LedgerBet) (LedgerBet, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bet.ID = 1
+	if n := len(l.bets); n > 0 {
+		bet.ID = l.bets[n-1].ID + 1
+	}
+	bet.PlacedAt = time.Now()
+	bet.Statut = BET_OPEN
+	l.bets = append(l.bets, bet)
+	return bet, l.save()
+}
+
+// "12@2.40" for a ladder option, "+9.5@1.85" / "-9.5@1.85" for an Over/Under line
+var betArgRegexp = regexp.MustCompile(`^([+-]?)(\d+(?:[.,]\d+)?)@(\d+(?:[.,]\d+)?)$`)
+
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(s, "€"), ",", "."), 64)
+}
+
+// /bet [matchId] 12@2.40 25€ - the match comes from the replied notification when not given
+func (l *betLedger) commandBet(repliedMatchID string, args []string, placedBy string) string {
+	matchID := repliedMatchID
+	if len(args) == 3 {
+		matchID, args = args[0], args[1:]
+	}
+	if len(args) != 2 || matchID == "" {
+		return "Usage : répondre à une notification avec /bet 12@2.40 25€ (ou /bet &lt;matchId&gt; 12@2.40 25€)"
+	}
+
+	found := betArgRegexp.FindStringSubmatch(args[0])
+	if found == nil {
+		return "❌ Pari invalide, format attendu : 12@2.40, +9.5@1.85 ou -9.5@1.85"
+	}
+	seuil, _ := parseDecimal(found[2])
+	cote, _ := parseDecimal(found[3])
+	stake, err := parseDecimal(args[1])
+	if err != nil || stake <= 0 {
+		return "❌ Mise invalide"
+	}
+	market := "paliers"
+	switch found[1] {
+	case "+":
+		market = "plus"
+	case "-":
+		market = "moins"
+	}
+
+	m, ok := recentMatches.get(matchID)
+	if !ok {
+		return fmt.Sprintf("❌ Match %s inconnu (plus affiché sur Winamax ?)", html.EscapeString(matchID))
+	}
+	bet, ok := findPlacedBet(recentMatches.families(matchID), market, seuil, cote, 0)
+	if !ok {
+		return fmt.Sprintf("❌ Aucun pari %s trouvé pour %s", html.EscapeString(args[0]), html.EscapeString(m.Match.Joueurs))
+	}
+	bet.Mise = stake
+	bet.PlacedBy = placedBy
+
+	saved, err := l.add(bet)
+	if err != nil {
+		return fmt.Sprintf("⚠️ Pari enregistré mais fichier non sauvegardé : %s", html.EscapeString(err.Error()))
+	}
+	return "✅ Pari enregistré\n" + saved.describe()
+}
+
+// Record the option of a value alert button
+func (l *betLedger) placeFromButton(matchID string, betTypeID int, seuil, cote, stake float64, placedBy string) string {
+	if stake <= 0 {
+		return "Répondez à l'alerte avec /bet seuil@cote mise"
+	}
+	families := recentMatches.families(matchID)
+	if len(families) == 0 {
+		return fmt.Sprintf("❌ Match %s inconnu", html.EscapeString(matchID))
+	}
+	bet, ok := findPlacedBet(families, "paliers", seuil, cote, betTypeID)
+	if !ok {
+		return "❌ Pari introuvable"
+	}
+	bet.Mise = stake
+	bet.PlacedBy = placedBy
+
+	saved, err := l.add(bet)
+	if err != nil {
+		return fmt.Sprintf("⚠️ Pari enregistré mais fichier non sauvegardé : %s", html.EscapeString(err.Error()))
+	}
+	return "✅ Pari enregistré\n" + saved.describe()
+}
+
+// Find the posted bet a /bet command refers to: the ladder option or Over/Under
+// line at that threshold in any family of the match, the closest cote deciding
+// between players
+func findPlacedBet(families map[string]MatchData, market string, seuil, cote float64, betTypeID int) (LedgerBet, bool) {
+	var best LedgerBet
+	bestDiff := math.Inf(1)
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, family := range names {
+		m := families[family]
+		// Bets are placed at the cote Winamax displays
+		consider := func(bet Bet, posted float64) {
+			posted = roundCote(posted)
+			if betTypeID != 0 && bet.BetTypeID != betTypeID {
+				return
+			}
+			if diff := math.Abs(posted - cote); diff < bestDiff {
+				bestDiff = diff
+				best = LedgerBet{
+					MatchID:   m.Match.MatchID,
+					Joueurs:   m.Match.Joueurs,
+					Family:    family,
+					Type:      bet.Type,
+					BetTypeID: bet.BetTypeID,
+					Side:      betSide(bet, m.Match),
+					Market:    market,
+					Seuil:     seuil,
+					Cote:      cote,
+				}
+			}
+		}
+
+		for _, bet := range m.Bet {
+			switch {
+			case market == "paliers" && bet.isLadder():
+				for _, opt := range bet.Options {
+					if opt.Seuil == seuil {
+						consider(bet, opt.Cote)
+					}
+				}
+			case market == "plus" && bet.isOverUnder() && bet.Cut == seuil:
+				consider(bet, bet.Plus)
+			case market == "moins" && bet.isOverUnder() && bet.Cut == seuil:
+				consider(bet, bet.Moins)
+			}
+		}
+	}
+	return best, !math.IsInf(bestDiff, 1)
+}
+
+func parseStatus(s string) (string, bool) {
+	switch strings.ToLower(s) {
+	case "gagné", "gagne", "won", "win", "w":
+		return BET_WON, true
+	case "perdu", "lost", "lose", "l":
+		return BET_LOST, true
+	case "remboursé", "rembourse", "void", "v":
+		return BET_VOID, true
+	}
+	return "", false
+}
+
+// /settle 12 gagné|perdu|remboursé
+func (l *betLedger) commandSettle(args []string) string {
+	if len(args) != 2 {
+		return "Usage : /settle &lt;id&gt; gagné|perdu|remboursé"
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	status, ok := parseStatus(args[1])
+	if err != nil || !ok {
+		return "Usage : /settle &lt;id&gt; gagné|perdu|remboursé"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.bets {
+		if l.bets[i].ID != id {
+			continue
+		}
+		now := time.Now()
+		l.bets[i].Statut = status
+		l.bets[i].SettledAt = &now
+		if err := l.save(); err != nil {
+			return fmt.Sprintf("⚠️ Fichier non sauvegardé : %s", html.EscapeString(err.Error()))
+		}
+		return fmt.Sprintf("%s → %s", l.bets[i].describe(), status)
+	}
+	return fmt.Sprintf("❌ Pari #%d inconnu", id)
+}
+
+// Market family of the configured sports with that name
+func familyNamed(name string) (MarketFamily, bool) {
+	for _, sport := range config.Sports {
+		for _, family := range sport.Markets {
+			if strings.EqualFold(family.Name, name) {
+				return family, true
+			}
+		}
+	}
+	return MarketFamily{}, false
+}
+
+// /result <matchId> [famille] <nombre joueur 1> <nombre joueur 2> settles the open
+// bets of the match in that family (aces when not given) with the counts of each
+// player. Bets recorded before the family was kept are recognised by their type.
+func (l *betLedger) commandResult(args []string) string {
+	usage := "Usage : /result &lt;matchId&gt; [famille] &lt;nombre joueur 1&gt; &lt;nombre joueur 2&gt;"
+	familyName := "aces"
+	switch len(args) {
+	case 3:
+	case 4:
+		familyName = args[1]
+		args = []string{args[0], args[2], args[3]}
+	default:
+		return usage
+	}
+	family, ok := familyNamed(familyName)
+	if !ok {
+		return fmt.Sprintf("❌ Famille %s inconnue", html.EscapeString(familyName))
+	}
+	player1, err1 := strconv.Atoi(args[1])
+	player2, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		return "❌ Résultat invalide"
+	}
+	counts := map[int]float64{0: float64(player1 + player2), 1: float64(player1), 2: float64(player2)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var lines []string
+	now := time.Now()
+	for i := range l.bets {
+		bet := &l.bets[i]
+		if bet.MatchID != args[0] || bet.Statut != BET_OPEN {
+			continue
+		}
+		if bet.Family != family.Name && (bet.Family != "" || !family.matches(bet.Type, bet.BetTypeID)) {
+			continue
+		}
+		bet.Statut = settle(bet.Market, bet.Seuil, counts[bet.Side])
+		bet.SettledAt = &now
+		lines = append(lines, fmt.Sprintf("%s → %s", bet.describe(), bet.Statut))
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("Aucun pari %s ouvert sur le match %s", html.EscapeString(family.Name), html.EscapeString(args[0]))
+	}
+	if err := l.save(); err != nil {
+		lines = append(lines, fmt.Sprintf("⚠️ Fichier non sauvegardé : %s", html.EscapeString(err.Error())))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Status of a bet once the count is known. An Over/Under line on a whole
+// number is refunded when the count lands on it.
+func settle(market string, seuil, count float64) string {
+	won := false
+	switch market {
+	case "plus", "moins":
+		if count == seuil {
+			return BET_VOID
+		}
+		won = (market == "plus") == (count > seuil)
+	default:
+		won = count >= seuil
+	}
+	if won {
+		return BET_WON
+	}
+	return BET_LOST
+}
+
+type ledgerTotals struct {
+	count  int
+	stake  float64
+	payout float64
+}
+
+func (t *ledgerTotals) add(bet LedgerBet) {
+	t.count++
+	t.stake += bet.Mise
+	t.payout += bet.payout()
+}
+
+func (t ledgerTotals) pnl() float64 {
+	return t.payout - t.stake
+}
+
+func (t ledgerTotals) roi() float64 {
+	if t.stake == 0 {
+		return 0
+	}
+	return t.pnl() / t.stake
+}
+
+func (t ledgerTotals) format() string {
+	return fmt.Sprintf("%d paris, mise %.2f€, P&amp;L %+.2f€, ROI %+.1f%%", t.count, t.stake, t.pnl(), t.roi()*100)
+}
+
+func addToGroup(groups map[string]*ledgerTotals, key string, bet LedgerBet) {
+	if groups[key] == nil {
+		groups[key] = &ledgerTotals{}
+	}
+	groups[key].add(bet)
+}
+
+// /pnl and /roi: settled bets overall, by market type and by player, sorted by
+// profit or by ROI
+func (l *betLedger) report(byROI bool) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var total ledgerTotals
+	open := 0
+	byMarket := make(map[string]*ledgerTotals)
+	byPlayer := make(map[string]*ledgerTotals)
+	for _, bet := range l.bets {
+		if bet.Statut == BET_OPEN {
+			open++
+			continue
+		}
+		total.add(bet)
+		addToGroup(byMarket, bet.marketLabel(), bet)
+		addToGroup(byPlayer, bet.player(), bet)
+	}
+
+	var message strings.Builder
+	title := "💰 <b>P&amp;L</b>"
+	if byROI {
+		title = "📈 <b>ROI</b>"
+	}
+	message.WriteString(fmt.Sprintf("%s\n%s\n%d pari(s) en cours\n", title, total.format(), open))
+
+	section := func(name string, groups map[string]*ledgerTotals) {
+		if len(groups) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if byROI {
+				return groups[keys[i]].roi() > groups[keys[j]].roi()
+			}
+			return groups[keys[i]].pnl() > groups[keys[j]].pnl()
+		})
+		message.WriteString(fmt.Sprintf("\n<b>%s :</b>\n", name))
+		for _, key := range keys {
+			message.WriteString(fmt.Sprintf("%s : %s\n", html.EscapeString(key), groups[key].format()))
+		}
+	}
+	section("Par marché", byMarket)
+	section("Par joueur", byPlayer)
+	return message.String()
+}
+
+// Latest results of every match, so bot commands can find the bet a notification showed
+type matchSnapshots struct {
+	mu      sync.Mutex
+	matches map[string]map[string]MatchData // matchId -> family -> data
+	seen    map[string]time.Time
+}
+
+var recentMatches = &matchSnapshots{
+	matches: make(map[string]map[string]MatchData),
+	seen:    make(map[string]time.Time),
+}
+
+func (s *matchSnapshots) update(family MarketFamily, results []MatchData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, m := range results {
+		if m.Match.MatchID == "" {
+			continue
+		}
+		if s.matches[m.Match.MatchID] == nil {
+			s.matches[m.Match.MatchID] = make(map[string]MatchData)
+		}
+		s.matches[m.Match.MatchID][family.Name] = m
+		s.seen[m.Match.MatchID] = now
+	}
+	// Forget matches gone for two days
+	for id, at := range s.seen {
+		if now.Sub(at) > 48*time.Hour {
+			delete(s.matches, id)
+			delete(s.seen, id)
+		}
+	}
+}
+
+// The followed bets of a match per family name, nil when unknown
+func (s *matchSnapshots) families(matchID string) map[string]MatchData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.matches[matchID]) == 0 {
+		return nil
+	}
+	families := make(map[string]MatchData, len(s.matches[matchID]))
+	for name, m := range s.matches[matchID] {
+		families[name] = m
+	}
+	return families
+}
+
+// All the followed bets of a match, whatever their family
+func (s *matchSnapshots) get(matchID string) (MatchData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	families, ok := s.matches[matchID]
+	if !ok {
+		return MatchData{}, false
+	}
+	var merged MatchData
+	for _, m := range families {
+		merged.Match = m.Match
+		merged.Bet = append(merged.Bet, m.Bet...)
+	}
+	return merged, true
+}
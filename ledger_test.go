@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		market string
+		seuil  float64
+		count  float64
+		want   string
+	}{
+		{"paliers", 10, 10, BET_WON},
+		{"paliers", 10, 9, BET_LOST},
+		{"plus", 9.5, 10, BET_WON},
+		{"plus", 9.5, 9, BET_LOST},
+		{"moins", 9.5, 9, BET_WON},
+		{"moins", 9.5, 10, BET_LOST},
+		// A whole-number line landing on the count is refunded on both sides
+		{"plus", 10, 10, BET_VOID},
+		{"moins", 10, 10, BET_VOID},
+		{"plus", 10, 11, BET_WON},
+		{"moins", 10, 11, BET_LOST},
+	}
+	for _, tt := range tests {
+		if got := settle(tt.market, tt.seuil, tt.count); got != tt.want {
+			t.Errorf("settle(%s, %g, %g) = %s, want %s", tt.market, tt.seuil, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestCommandResult(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config.Sports = []SportConfig{{Name: "tennis", Markets: []MarketFamily{
+		{Name: "aces", Keywords: []string{"nombre d'aces"}},
+		{Name: "jeux", Keywords: []string{"nombre de jeux"}},
+	}}}
+
+	open := func(id int, family, betType string, side int, market string, seuil, cote float64) LedgerBet {
+		return LedgerBet{ID: id, MatchID: "42", Family: family, Type: betType, Side: side, Market: market, Seuil: seuil, Cote: cote, Mise: 10, Statut: BET_OPEN}
+	}
+	l := &betLedger{bets: []LedgerBet{
+		open(1, "aces", "Nombre d'aces de A", 1, "plus", 10, 1.9),   // 10 aces: refunded
+		open(2, "aces", "Nombre d'aces de A", 1, "moins", 10, 1.9),  // refunded
+		open(3, "aces", "Nombre d'aces de B", 2, "paliers", 8, 2.5), // 8 aces: won
+		open(4, "aces", "Nombre d'aces", 0, "plus", 18.5, 1.8),      // 18 in total: lost
+		open(5, "", "Nombre d'aces de B", 2, "moins", 7.5, 2),       // no family recorded, known by its type: lost
+		open(6, "jeux", "Nombre de jeux", 0, "plus", 20.5, 1.9),     // another family: left open
+	}}
+	l.bets = append(l.bets, open(7, "aces", "Nombre d'aces de A", 1, "plus", 9.5, 2))
+	l.bets[6].MatchID = "43" // another match
+
+	reply := l.commandResult([]string{"42", "10", "8"})
+	want := map[int]string{1: BET_VOID, 2: BET_VOID, 3: BET_WON, 4: BET_LOST, 5: BET_LOST, 6: BET_OPEN, 7: BET_OPEN}
+	for _, bet := range l.bets {
+		if bet.Statut != want[bet.ID] {
+			t.Errorf("bet #%d: %s, want %s", bet.ID, bet.Statut, want[bet.ID])
+		}
+		if (bet.Statut == BET_OPEN) != (bet.SettledAt == nil) {
+			t.Errorf("bet #%d: settled at %v with status %s", bet.ID, bet.SettledAt, bet.Statut)
+		}
+	}
+	if lines := strings.Count(reply, "\n") + 1; lines != 5 {
+		t.Errorf("reply has %d lines, want 5:\n%s", lines, reply)
+	}
+
+	// Refunds return the stake: 2 × 10 refunded + 10 × 2.5 won, for 50 staked
+	var totals ledgerTotals
+	for _, bet := range l.bets {
+		if bet.Statut != BET_OPEN {
+			totals.add(bet)
+		}
+	}
+	if math.Abs(totals.pnl()-(-5)) > 1e-9 {
+		t.Errorf("P&L = %v, want -5", totals.pnl())
+	}
+
+	// The jeux bet with the family given, then nothing left to settle
+	l.commandResult([]string{"42", "jeux", "12", "10"})
+	if l.bets[5].Statut != BET_WON {
+		t.Errorf("jeux bet: %s, want %s", l.bets[5].Statut, BET_WON)
+	}
+	if reply := l.commandResult([]string{"42", "10", "8"}); !strings.HasPrefix(reply, "Aucun pari") {
+		t.Errorf("second /result = %q, want nothing to settle", reply)
+	}
+
+	for _, args := range [][]string{{"42", "10"}, {"42", "x", "8"}, {"42", "sets", "1", "2"}} {
+		if reply := l.commandResult(args); !strings.HasPrefix(reply, "Usage") && !strings.HasPrefix(reply, "❌") {
+			t.Errorf("/result %v = %q, want an error", args, reply)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Match lifecycle states, in order
+const (
+	STATE_DISCOVERED   = "discovered"   // seen on the sport page
+	STATE_MARKETS_OPEN = "markets_open" // a followed market family wants it
+	STATE_STARTED      = "started"
+	STATE_FINISHED     = "finished"
+	STATE_REMOVED      = "removed" // gone (or cancelled) before it started
+)
+
+// Finished and removed matches are appended here, one JSON object per line
+const MATCH_ARCHIVE_FILE = "match_archive.jsonl"
+
+// A match missing from the sport page is only finished or removed once it has
+// been missing for this many cycles in a row and for at least this long, so a
+// partial page doesn't end it
+const (
+	MISSING_CYCLES   = 3
+	MISSING_DURATION = 2 * time.Minute
+)
+
+// What the sport page says about a match, followed or not
+type MatchStatus struct {
+	Match
+	Status     string // PREMATCH, LIVE...
+	Period     string
+	Available  bool
+	HasMarkets bool // a followed family wants this match
+}
+
+type stateChange struct {
+	State string    `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+type matchLifecycle struct {
+	MatchID    string        `json:"matchId"`
+	Joueurs    string        `json:"joueurs"`
+	MatchStart int64         `json:"matchStart,omitempty"`
+	Status     string        `json:"status,omitempty"`
+	Period     string        `json:"period,omitempty"`
+	Available  bool          `json:"available"`
+	State      string        `json:"state"`
+	History    []stateChange `json:"history"`
+	Archived   bool          `json:"archived,omitempty"`
+	LastSeen   time.Time     `json:"lastSeen"`
+	Missed     int           `json:"missed,omitempty"` // cycles in a row without it on the sport page
+
+	// Kept for the buttons and mutes of live alerts
+	Lien          string `json:"lien,omitempty"`
+	Competitor1ID int64  `json:"competitor1Id,omitempty"`
+	Competitor2ID int64  `json:"competitor2Id,omitempty"`
+	TournamentID  int64  `json:"tournamentId,omitempty"`
+	Tournoi       string `json:"tournoi,omitempty"`
+}
+
+func (l *matchLifecycle) moveTo(state string, at time.Time) {
+	l.State = state
+	l.History = append(l.History, stateChange{State: state, At: at})
+}
+
+func (l *matchLifecycle) done() bool {
+	return l.State == STATE_FINISHED || l.State == STATE_REMOVED
+}
+
+func (l *matchLifecycle) started() bool {
+	return l.State == STATE_STARTED || l.State == STATE_FINISHED
+}
+
+// Lifecycle of every match of one watcher, saved after each change
+type lifecycleTracker struct {
+	path    string
+	matches map[string]*matchLifecycle
+}
+
+var archiveMu sync.Mutex
+
+func newLifecycleTracker(id string) *lifecycleTracker {
+	t := &lifecycleTracker{
+		path:    fmt.Sprintf("lifecycle_%s.json", id),
+		matches: make(map[string]*matchLifecycle),
+	}
+	if data, err := os.ReadFile(t.path); err == nil {
+		if err := json.Unmarshal(data, &t.matches); err != nil {
+			slog.Warn("Failed to read match lifecycles, starting over", "file", t.path, "err", err)
+			t.matches = make(map[string]*matchLifecycle)
+		}
+	}
+	return t
+}
+
+func isFinishedStatus(status string) bool {
+	s := strings.ToUpper(status)
+	return strings.Contains(s, "END") || strings.Contains(s, "FINISH") || strings.Contains(s, "CLOSE")
+}
+
+func isCancelledStatus(status string) bool {
+	s := strings.ToUpper(status)
+	return strings.Contains(s, "CANCEL") || strings.Contains(s, "ABANDON") || strings.Contains(s, "POSTPONE")
+}
+
+// Without a status, a match counts as started once its start time has passed
+func isStartedStatus(status string, matchStart int64, now time.Time) bool {
+	if status == "" {
+		return matchStart > 0 && now.Unix() >= matchStart
+	}
+	return !strings.EqualFold(status, "PREMATCH") && !isFinishedStatus(status) && !isCancelledStatus(status)
+}
+
+// Move every match along with what the sport page said this cycle. Matches
+// missing from the page for MISSING_CYCLES cycles and MISSING_DURATION are
+// finished when they had started, removed otherwise; both are archived and forgotten.
+// Returns the matches that ended, so the state kept for their alerts can go too.
+func (t *lifecycleTracker) update(seen []MatchStatus, lg *slog.Logger) []Match {
+	var ended []Match
+	now := time.Now()
+	present := make(map[string]bool)
+	changed := false
+
+	for _, s := range seen {
+		if s.MatchID == "" {
+			continue
+		}
+		present[s.MatchID] = true
+		l, ok := t.matches[s.MatchID]
+		if !ok {
+			l = &matchLifecycle{MatchID: s.MatchID}
+			l.moveTo(STATE_DISCOVERED, now)
+			t.matches[s.MatchID] = l
+			changed = true
+		}
+		if l.Status != s.Status || l.Period != s.Period || l.Available != s.Available || l.MatchStart != s.MatchStart {
+			changed = true
+		}
+		l.Joueurs, l.MatchStart, l.Lien = s.Joueurs, s.MatchStart, s.Lien
+		l.Competitor1ID, l.Competitor2ID = s.Competitor1ID, s.Competitor2ID
+		l.TournamentID, l.Tournoi = s.TournamentID, s.Tournoi
+		l.Status, l.Period, l.Available = s.Status, s.Period, s.Available
+		l.LastSeen, l.Missed = now, 0
+
+		if s.HasMarkets && l.State == STATE_DISCOVERED {
+			l.moveTo(STATE_MARKETS_OPEN, now)
+			lg.Debug("Match markets open", "matchId", s.MatchID)
+		}
+		switch {
+		case isFinishedStatus(s.Status):
+			if !l.done() {
+				l.moveTo(STATE_FINISHED, now)
+			}
+		case isCancelledStatus(s.Status):
+			if !l.done() {
+				l.moveTo(STATE_REMOVED, now)
+			}
+		case !l.started() && isStartedStatus(s.Status, s.MatchStart, now):
+			l.moveTo(STATE_STARTED, now)
+			lg.Debug("Match started", "matchId", s.MatchID, "status", s.Status)
+		}
+	}
+
+	for id, l := range t.matches {
+		if !present[id] && !l.done() {
+			if l.LastSeen.IsZero() {
+				l.LastSeen = now
+			}
+			l.Missed++
+			changed = true
+			if l.Missed < MISSING_CYCLES || now.Sub(l.LastSeen) < MISSING_DURATION {
+				lg.Debug("Match missing from the sport page", "matchId", id, "missed", l.Missed)
+				continue
+			}
+			if l.started() {
+				l.moveTo(STATE_FINISHED, now)
+			} else {
+				l.moveTo(STATE_REMOVED, now)
+			}
+		}
+		if l.done() && !l.Archived {
+			ended = append(ended, Match{MatchID: l.MatchID, Joueurs: l.Joueurs, Lien: l.Lien})
+			if err := archiveLifecycle(l); err != nil {
+				lg.Warn("Failed to archive match", "matchId", id, "err", err)
+				continue
+			}
+			l.Archived = true
+			changed = true
+		}
+		// Finished matches stay while the page still lists them, so they aren't discovered again
+		if l.Archived && !present[id] {
+			delete(t.matches, id)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := t.save(); err != nil {
+			lg.Warn("Failed to save match lifecycles", "file", t.path, "err", err)
+		}
+	}
+	return ended
+}
+
+func (t *lifecycleTracker) save() error {
+	data, err := json.MarshalIndent(t.matches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0644)
+}
+
+func archiveLifecycle(l *matchLifecycle) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	f, err := os.OpenFile(MATCH_ARCHIVE_FILE, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Keep only the matches that haven't started, for prematch diffing and alerts
+func (t *lifecycleTracker) prematch(results []MatchData) []MatchData {
+	var kept []MatchData
+	for _, m := range results {
+		if l, ok := t.matches[m.Match.MatchID]; ok && l.started() {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// Started matches that had followed markets before the start, for the live watcher
+func (t *lifecycleTracker) live() []Match {
+	var matches []Match
+	for _, l := range t.matches {
+		if l.State != STATE_STARTED {
+			continue
+		}
+		for _, change := range l.History {
+			if change.State == STATE_MARKETS_OPEN {
+				matches = append(matches, Match{
+					MatchID:       l.MatchID,
+					Joueurs:       l.Joueurs,
+					Lien:          l.Lien,
+					MatchStart:    l.MatchStart,
+					Competitor1ID: l.Competitor1ID,
+					Competitor2ID: l.Competitor2ID,
+					TournamentID:  l.TournamentID,
+					Tournoi:       l.Tournoi,
+				})
+				break
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLifecycleTransitions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	lg := slog.New(slog.DiscardHandler)
+	tracker := newLifecycleTracker("test")
+	state := func(id string) string {
+		if l, ok := tracker.matches[id]; ok {
+			return l.State
+		}
+		return ""
+	}
+	// Missing long enough: the time condition is met, only the cycle count is left
+	seenLongAgo := func(ids ...string) {
+		for _, id := range ids {
+			tracker.matches[id].LastSeen = time.Now().Add(-MISSING_DURATION - time.Minute)
+		}
+	}
+	prematch := func(id string, markets bool) MatchStatus {
+		return MatchStatus{Match: Match{MatchID: id, Lien: "/match/" + id}, Status: "PREMATCH", HasMarkets: markets}
+	}
+
+	ended := tracker.update([]MatchStatus{
+		prematch("1", true),
+		prematch("2", false),
+		prematch("3", true),
+		{Match: Match{MatchID: "4"}, Status: "CANCELLED"},
+	}, lg)
+	for id, want := range map[string]string{"1": STATE_MARKETS_OPEN, "2": STATE_DISCOVERED, "3": STATE_MARKETS_OPEN} {
+		if got := state(id); got != want {
+			t.Errorf("match %s: state %q, want %q", id, got, want)
+		}
+	}
+	// A cancelled match is removed and archived right away, then kept while listed
+	if len(ended) != 1 || ended[0].MatchID != "4" || state("4") != STATE_REMOVED {
+		t.Errorf("cancelled match: ended %v, state %q", ended, state("4"))
+	}
+
+	live := MatchStatus{Match: Match{MatchID: "1", Lien: "/match/1"}, Status: "LIVE"}
+	tracker.update([]MatchStatus{live, prematch("2", true), prematch("3", true)}, lg)
+	if state("1") != STATE_STARTED || state("2") != STATE_MARKETS_OPEN {
+		t.Errorf("states %q and %q, want started and markets open", state("1"), state("2"))
+	}
+	if got := tracker.live(); len(got) != 1 || got[0].MatchID != "1" {
+		t.Errorf("live() = %v, want match 1", got)
+	}
+	kept := tracker.prematch([]MatchData{{Match: Match{MatchID: "1"}}, {Match: Match{MatchID: "2"}}})
+	if len(kept) != 1 || kept[0].Match.MatchID != "2" {
+		t.Errorf("prematch() kept %v, want match 2 only", kept)
+	}
+
+	// Missing from the page: ended only after MISSING_CYCLES cycles
+	seenLongAgo("1", "2")
+	for cycle := 1; cycle < MISSING_CYCLES; cycle++ {
+		if ended := tracker.update([]MatchStatus{prematch("3", true)}, lg); len(ended) != 0 {
+			t.Fatalf("cycle %d: ended %v, want none yet", cycle, ended)
+		}
+	}
+	if state("1") != STATE_STARTED || state("2") != STATE_MARKETS_OPEN {
+		t.Errorf("states %q and %q before the last missing cycle", state("1"), state("2"))
+	}
+	ended = tracker.update([]MatchStatus{prematch("3", true)}, lg)
+	if len(ended) != 2 {
+		t.Fatalf("ended %v, want matches 1 and 2", ended)
+	}
+	// Archived and forgotten once no longer listed
+	if state("1") != "" || state("2") != "" {
+		t.Errorf("states %q and %q, want both forgotten", state("1"), state("2"))
+	}
+
+	// Missing for enough cycles but not for long enough: still there
+	for cycle := 0; cycle < MISSING_CYCLES+1; cycle++ {
+		if ended := tracker.update(nil, lg); len(ended) != 0 {
+			t.Fatalf("match 3 ended after %d quick cycles", cycle+1)
+		}
+	}
+	seenLongAgo("3")
+	if ended := tracker.update(nil, lg); len(ended) != 1 || ended[0].MatchID != "3" {
+		t.Errorf("ended %v, want match 3", ended)
+	}
+}
+
+func TestLifecycleFinishedStatus(t *testing.T) {
+	t.Chdir(t.TempDir())
+	lg := slog.New(slog.DiscardHandler)
+	tracker := newLifecycleTracker("test")
+	tracker.update([]MatchStatus{{Match: Match{MatchID: "1"}, Status: "LIVE", HasMarkets: true}}, lg)
+	ended := tracker.update([]MatchStatus{{Match: Match{MatchID: "1"}, Status: "ENDED"}}, lg)
+	if len(ended) != 1 || tracker.matches["1"].State != STATE_FINISHED || !tracker.matches["1"].Archived {
+		t.Errorf("ended %v, state %+v, want finished and archived", ended, tracker.matches["1"])
+	}
+	// Still listed as ended: not reported again
+	if ended := tracker.update([]MatchStatus{{Match: Match{MatchID: "1"}, Status: "ENDED"}}, lg); len(ended) != 0 {
+		t.Errorf("ended %v again", ended)
+	}
+}
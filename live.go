@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// In-play monitoring of matches whose followed markets were open before the start
+type LiveConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Channel      string `json:"channel"`       // in-play alerts, kept apart from prematch ones
+	EverySeconds int    `json:"every_seconds"` // polling interval of live matches
+}
+
+// Live matches of every watcher, set by the prematch pipeline from the lifecycle
+type liveRegistry struct {
+	mu      sync.Mutex
+	matches map[string][]Match // watcher ID -> started matches
+}
+
+var liveMatches = &liveRegistry{matches: make(map[string][]Match)}
+
+func (r *liveRegistry) set(id string, matches []Match) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matches[id] = matches
+}
+
+func (r *liveRegistry) get(id string) []Match {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Match(nil), r.matches[id]...)
+}
+
+// Poll the live matches of a Winamax watcher and alert when followed markets
+// open, get suspended (gone or unpriced) and reopen. The session is kept
+// between cycles and only reopened after a cycle where every match failed.
+func runLiveWatcher(w *winamaxBook, logger *slog.Logger) {
+	every := time.Duration(config.Live.EverySeconds) * time.Second
+	if every <= 0 {
+		every = 5 * time.Second
+	}
+
+	var session *winamaxSession
+	open := make(map[string]map[string]Bet)  // matchId -> line key -> last priced bet, absent = suspended
+	seen := make(map[string]map[string]bool) // matchId -> line keys ever priced
+
+	for {
+		time.Sleep(every)
+
+		matches := w.liveMatches()
+		live := make(map[string]bool)
+		for _, m := range matches {
+			live[m.MatchID] = true
+		}
+		for id := range open {
+			if !live[id] {
+				delete(open, id)
+				delete(seen, id)
+			}
+		}
+		if len(matches) == 0 {
+			session = nil
+			continue
+		}
+
+		cycleLog := logger.With("trace", newTraceID(), "stage", "live")
+		if session == nil {
+			var err error
+			if session, err = w.openSession(cycleLog); err != nil {
+				cycleLog.Warn("⚠️ Échec session live", "err", err)
+				continue
+			}
+		}
+
+		failures := 0
+		for _, m := range matches {
+			matchLog := cycleLog.With("matchId", m.MatchID, "proxy", session.proxy)
+			if !matchRequests.take(time.Now()) {
+				matchLog.Debug("Request budget spent, skipping live match")
+				continue
+			}
+			familyBets, ok := w.fetchMatchBets(session, m.MatchID, matchLog)
+			if !ok {
+				failures++
+				continue
+			}
+
+			current := make(map[string]Bet)
+			for _, bets := range familyBets {
+				for _, bet := range bets {
+					current[generateLineKey(bet)] = bet
+				}
+			}
+			if open[m.MatchID] == nil {
+				open[m.MatchID] = make(map[string]Bet)
+				seen[m.MatchID] = make(map[string]bool)
+			}
+
+			var events []string
+			for key, bet := range current {
+				if _, wasOpen := open[m.MatchID][key]; !wasOpen {
+					label := "🆕 Ouvert"
+					if seen[m.MatchID][key] {
+						label = "▶️ Rouvert"
+					}
+					events = append(events, label+" : "+formatBet(bet))
+				}
+			}
+			for key := range open[m.MatchID] {
+				if _, stillOpen := current[key]; !stillOpen {
+					events = append(events, fmt.Sprintf("⏸️ Suspendu : <b>%s</b>", html.EscapeString(strings.Replace(key, "|", " ", 1))))
+				}
+			}
+			open[m.MatchID] = current
+			for key := range current {
+				seen[m.MatchID][key] = true
+			}
+			if len(events) == 0 || preferences.muted(config.Live.Channel, m) {
+				continue
+			}
+
+			message := fmt.Sprintf("🔴 <b>%s</b> (en direct)\n\n%s\n🔗 <a href=\"%s\">LIEN</a>",
+				html.EscapeString(m.displayTitle()), strings.Join(events, "\n"), w.book.matchLink(m.MatchID))
+			if outcome, err := deliverAlert(matchLog, config.Live.Channel, "en direct", m, message, PARSE_MODE_HTML, alertKeyboard(m)); err != nil {
+				matchLog.Error("Failed to send live alert", "err", err)
+			} else if outcome == DELIVERY_SENT {
+				matchLog.Info("Live alert sent", "events", len(events))
+			}
+		}
+
+		if failures == len(matches) {
+			session = nil
+		}
+	}
+}
+
+// Started matches of this watcher that at least one family alerts on (see MarketFamily.Doubles)
+func (w *winamaxBook) liveMatches() []Match {
+	var matches []Match
+	for _, m := range liveMatches.get(w.ID()) {
+		if slices.ContainsFunc(w.markets, func(f MarketFamily) bool { return f.alertsOn(m) }) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Telegram refuses messages longer than 4096 characters
+const TELEGRAM_MAX_MESSAGE = 4000
+
+var telegramLogs *telegramSink
+
+// Build the process logger: console output (text or JSON) plus the throttled
+// Telegram handler that forwards records at or above the configured level
+func setupLogger(cfg LogConfig, out io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(cfg.Level, slog.LevelInfo)}
+
+	var console slog.Handler
+	if strings.EqualFold(cfg.Format, "json") {
+		console = slog.NewJSONHandler(out, opts)
+	} else {
+		console = slog.NewTextHandler(out, opts)
+	}
+
+	every := time.Duration(cfg.TelegramEverySeconds) * time.Second
+	if every <= 0 {
+		every = 3 * time.Second
+	}
+	telegramLogs = newTelegramSink(LOG_CHANNEL, every)
+	telegram := &telegramHandler{
+		level: parseLevel(cfg.TelegramLevel, slog.LevelInfo),
+		sink:  telegramLogs,
+	}
+
+	// Digest mode: routine info goes into a periodic summary, only warnings and
+	// errors are forwarded right away (once per window, repeats are counted)
+	if cfg.Digest {
+		telegram.level = max(telegram.level.Level(), slog.LevelWarn)
+		telegram.digest = digest
+		digestEvery := time.Duration(cfg.DigestEveryMinutes) * time.Minute
+		if digestEvery <= 0 {
+			digestEvery = time.Hour
+		}
+		go runDigest(LOG_CHANNEL, digestEvery)
+	}
+
+	logger := slog.New(&multiHandler{handlers: []slog.Handler{console, telegram}})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Short random ID attached to every record of a scraping cycle
+func newTraceID() string {
+	return uuid.New().String()[:8]
+}
+
+// Fan a record out to several handlers
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (m *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (m *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// Formats records as short HTML lines and hands them to the sink, never blocking the scraper
+type telegramHandler struct {
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string       // group prefix for attribute keys
+	digest *digestStats // set in digest mode to collapse duplicate errors
+	sink   *telegramSink
+}
+
+func (h *telegramHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *telegramHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.digest != nil && !h.digest.firstOccurrence(r.Level, r.Message, h.errText(r)) {
+		return nil
+	}
+
+	var line strings.Builder
+	line.WriteString(fmt.Sprintf("%s [%s] %s", levelEmoji(r.Level), r.Time.Format("15:04:05"), html.EscapeString(r.Message)))
+
+	var fields []string
+	add := func(a slog.Attr) {
+		// The trace ID is only useful to grep the console logs
+		if a.Key == "trace" || a.Equal(slog.Attr{}) {
+			return
+		}
+		fields = append(fields, fmt.Sprintf("%s=%s", a.Key, html.EscapeString(a.Value.String())))
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		a.Key = h.prefix + a.Key
+		add(a)
+		return true
+	})
+	if len(fields) > 0 {
+		line.WriteString(" <i>" + strings.Join(fields, " ") + "</i>")
+	}
+
+	h.sink.push(line.String())
+	return nil
+}
+
+// The err attribute of a record, so different errors under one message are told apart
+func (h *telegramHandler) errText(r slog.Record) string {
+	var text string
+	for _, a := range h.attrs {
+		if a.Key == "err" {
+			text = a.Value.String()
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "err" {
+			text = a.Value.String()
+			return false
+		}
+		return true
+	})
+	return text
+}
+
+func (h *telegramHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		clone.attrs = append(clone.attrs, a)
+	}
+	return &clone
+}
+
+func (h *telegramHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func levelEmoji(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "❌"
+	case level >= slog.LevelWarn:
+		return "⚠️"
+	case level >= slog.LevelInfo:
+		return "🔔"
+	default:
+		return "🐞"
+	}
+}
+
+// Buffers log lines and posts them in batches, at most one message per interval,
+// so a burst of errors doesn't hit Telegram's rate limit or stall the scraping loop
+type telegramSink struct {
+	chatID  string
+	every   time.Duration
+	queue   chan string
+	dropped atomic.Int64
+}
+
+func newTelegramSink(chatID string, every time.Duration) *telegramSink {
+	s := &telegramSink{
+		chatID: chatID,
+		every:  every,
+		queue:  make(chan string, 256),
+	}
+	go s.run()
+	return s
+}
+
+func (s *telegramSink) push(line string) {
+	select {
+	case s.queue <- line:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *telegramSink) run() {
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+
+	var pending []string
+	send := func() {
+		// Drain whatever is already queued so it goes out in this batch
+		for {
+			select {
+			case line := <-s.queue:
+				pending = append(pending, line)
+				continue
+			default:
+			}
+			break
+		}
+		if n := s.dropped.Swap(0); n > 0 {
+			pending = append(pending, fmt.Sprintf("⚠️ %d ligne(s) de log ignorée(s) (file pleine)", n))
+		}
+		for _, message := range batchLines(pending, TELEGRAM_MAX_MESSAGE) {
+			if err := sendTelegramMessage(s.chatID, message); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to send log:", err)
+			}
+		}
+		pending = nil
+	}
+
+	for {
+		select {
+		case line := <-s.queue:
+			pending = append(pending, line)
+		case <-ticker.C:
+			if len(pending) > 0 || s.dropped.Load() > 0 {
+				send()
+			}
+		}
+	}
+}
+
+// Join lines into as few messages as possible without exceeding max characters
+func batchLines(lines []string, max int) []string {
+	var messages []string
+	var current strings.Builder
+	for _, line := range lines {
+		if len(line) > max {
+			line = truncateHTML(line, max)
+		}
+		if current.Len() > 0 && current.Len()+1+len(line) > max {
+			messages = append(messages, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		messages = append(messages, current.String())
+	}
+	return messages
+}
+
+// Cut an HTML line to at most max bytes: tags are dropped so none is left open,
+// and the text is cut on a rune boundary and escaped again
+func truncateHTML(line string, max int) string {
+	const ellipsis = "…"
+	text := stripTags(line)
+	if escaped := html.EscapeString(text); len(escaped) <= max {
+		return escaped
+	}
+	var cut strings.Builder
+	for _, r := range text {
+		escaped := html.EscapeString(string(r))
+		if cut.Len()+len(escaped)+len(ellipsis) > max {
+			return cut.String() + ellipsis
+		}
+		cut.WriteString(escaped)
+	}
+	return cut.String()
+}
@@ -4,22 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"math"
+	"log/slog"
 	"math/rand"
 	stdhttp "net/http"
 	"net/url"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	jsonrepair "github.com/RealAlexandreAI/json-repair"
-	http "github.com/bogdanfinn/fhttp"
-	tls_client "github.com/bogdanfinn/tls-client"
-	"github.com/bogdanfinn/tls-client/profiles"
-	"github.com/google/uuid"
 )
 
 // Telegram Configuration
@@ -29,32 +22,84 @@ const (
 	LOG_CHANNEL        = "-5053088058"
 )
 
+// Defaults for the French book - see BookConfig for other books
 var URL_BASE = "https://www.winamax.fr"
 var SocketURL = "https://sports-eu-west-3.winamax.fr"
 
-var SpanishPage = "/apuestas-deportivas/sports/5"
-var FrenchPage = "/paris-sportifs/sports/5"
+// Sport page paths, formatted with the sport ID (5 = tennis)
+var SpanishPage = "/apuestas-deportivas/sports/%d"
+var FrenchPage = "/paris-sportifs/sports/%d"
 
 // Files for comparison
 const CURRENT_FILE = "winamax_aces.json"
 const PAST_FILE = "winamax_aces_past.json"
 
 type Option struct {
-	Seuil float64 `json:"seuil"`
+	Seuil     float64 `json:"seuil"`
+	Cote      float64 `json:"cote"`
+	Marge     float64 `json:"marge,omitempty"`     // against the Over/Under line of the same threshold, when there is one
+	Proba     float64 `json:"proba,omitempty"`     // fair probability from the fitted count model
+	CoteJuste float64 `json:"coteJuste,omitempty"` // fair cote, 1/proba
+	Value     float64 `json:"value,omitempty"`     // expected value of a 1€ stake at the offered cote
+}
+
+// One priced outcome of a bet, as labelled by Winamax
+type Outcome struct {
+	Code  string  `json:"code,omitempty"`
+	Label string  `json:"label"`
 	Cote  float64 `json:"cote"`
 }
 
+// Cut/Plus/Moins are set for OverUnder, Options for dynamic (paliers), Handicap for
+// asian_handicap; every template keeps its raw Outcomes. Marge and the implied
+// probabilities are computed from the cotes (see pricing.go).
 type Bet struct {
-	Type    string   `json:"type"`
-	Cut     float64  `json:"cut,omitempty"`
-	Plus    float64  `json:"plus,omitempty"`
-	Moins   float64  `json:"moins,omitempty"`
-	Options []Option `json:"options,omitempty"`
+	Type       string    `json:"type"`
+	BetTypeID  int       `json:"betTypeId,omitempty"` // same in every language, used to compare books
+	Template   string    `json:"template,omitempty"`
+	Cut        float64   `json:"cut,omitempty"`
+	Plus       float64   `json:"plus,omitempty"`
+	Moins      float64   `json:"moins,omitempty"`
+	ProbaPlus  float64   `json:"probaPlus,omitempty"`  // margin-free probability of over
+	ProbaMoins float64   `json:"probaMoins,omitempty"` // margin-free probability of under
+	Marge      float64   `json:"marge,omitempty"`      // overround, 0.05 = 5%
+	Handicap   float64   `json:"handicap,omitempty"`
+	Options    []Option  `json:"options,omitempty"`
+	Outcomes   []Outcome `json:"outcomes,omitempty"`
+
+	CompetitorID int64 `json:"competitorId,omitempty"` // player the bet is about, 0 for the match (see players.go)
 }
 
 type Match struct {
-	Joueurs string `json:"joueurs"`
-	Lien    string `json:"lien"`
+	MatchID       string `json:"matchId,omitempty"`
+	Joueurs       string `json:"joueurs"`
+	Lien          string `json:"lien"`
+	MatchStart    int64  `json:"matchStart,omitempty"` // unix seconds
+	Competitor1ID int64  `json:"competitor1Id,omitempty"`
+	Competitor2ID int64  `json:"competitor2Id,omitempty"`
+	TournamentID  int64  `json:"tournamentId,omitempty"`
+	Tournoi       string `json:"tournoi,omitempty"`
+	Categorie     string `json:"categorie,omitempty"` // ATP, WTA, Challenger, ITF...
+	Tour          string `json:"tour,omitempty"`      // round name as listed, e.g. "1/8 de finale"
+}
+
+// Tournament, category, round and start of a match on one line, "" when none is known
+func (m Match) details() string {
+	var parts []string
+	tournament := strings.TrimSpace(m.Categorie + " " + m.Tournoi)
+	if m.Categorie != "" && strings.Contains(strings.ToLower(m.Tournoi), strings.ToLower(m.Categorie)) {
+		tournament = m.Tournoi
+	}
+	if tournament != "" {
+		parts = append(parts, "🏆 "+tournament)
+	}
+	if m.Tour != "" {
+		parts = append(parts, m.Tour)
+	}
+	if m.MatchStart > 0 {
+		parts = append(parts, "🕒 "+time.Unix(m.MatchStart, 0).Format("02/01 15:04"))
+	}
+	return strings.Join(parts, " · ")
 }
 
 type MatchData struct {
@@ -64,36 +109,41 @@ type MatchData struct {
 
 // Send message to Telegram using standard HTTP client
 func sendTelegramMessage(chatID, message string) error {
-	telegramURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", TELEGRAM_BOT_TOKEN)
+	return sendTelegramMessageAs(chatID, message, PARSE_MODE_HTML)
+}
 
+// Send a message written for a parse mode (HTML or MarkdownV2)
+func sendTelegramMessageAs(chatID, message, parseMode string) error {
 	data := url.Values{}
 	data.Set("chat_id", chatID)
 	data.Set("text", message)
-	data.Set("parse_mode", "HTML")
+	data.Set("parse_mode", parseMode)
 	data.Set("disable_web_page_preview", "true")
 
+	_, err := callTelegram("sendMessage", data, 10*time.Second)
+	return err
+}
+
+// Call a Bot API method and return the raw response body
+func callTelegram(method string, data url.Values, timeout time.Duration) ([]byte, error) {
+	telegramURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", TELEGRAM_BOT_TOKEN, method)
+
 	// Use standard net/http client for Telegram (not the TLS client)
-	client := &stdhttp.Client{Timeout: 10 * time.Second}
+	client := &stdhttp.Client{Timeout: timeout}
 	resp, err := client.Post(telegramURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram error: %s", string(body))
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-// Send log message
-func sendLog(message string) {
-	timestamp := time.Now().Format("15:04:05")
-	logMsg := fmt.Sprintf("🔔 [%s] %s", timestamp, message)
-	if err := sendTelegramMessage(LOG_CHANNEL, logMsg); err != nil {
-		log.Println("Failed to send log:", err)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("telegram error: %s", string(body))
 	}
+	return body, nil
 }
 
 // Generate bet key for comparison - uses only bet type name to avoid duplicates from incomplete scraping
@@ -103,17 +153,38 @@ func generateBetKey(bet Bet) string {
 	return bet.Type
 }
 
-// Generate a signature of the bet including cotes for comparison
+// Key of one line of a bet: Winamax lists alternate Over/Under lines (and
+// handicaps) under the same type name, so the cut or handicap tells them apart
+func generateLineKey(bet Bet) string {
+	switch {
+	case bet.isOverUnder():
+		return fmt.Sprintf("%s|%g", bet.Type, bet.Cut)
+	case bet.Handicap != 0:
+		return fmt.Sprintf("%s|%g", bet.Type, bet.Handicap)
+	}
+	return bet.Type
+}
+
+// Generate a signature of the bet including cotes for comparison. Cotes are
+// rounded as displayed, so alerts only repeat when what users see changes.
 func generateBetSignature(bet Bet) string {
 	if len(bet.Options) > 0 {
 		// For dynamic bets, include all thresholds and cotes
 		var parts []string
 		for _, opt := range bet.Options {
-			parts = append(parts, fmt.Sprintf("%.0f@%.2f", opt.Seuil, opt.Cote))
+			parts = append(parts, fmt.Sprintf("%.0f@%.2f", opt.Seuil, roundCote(opt.Cote)))
+		}
+		return fmt.Sprintf("%s|%s", bet.Type, strings.Join(parts, ","))
+	}
+	if bet.isOutcomeList() {
+		// For 2way/3way/handicap/score bets, include every label and cote
+		var parts []string
+		for _, oc := range bet.Outcomes {
+			parts = append(parts, fmt.Sprintf("%s@%.2f", oc.Label, roundCote(oc.Cote)))
 		}
 		return fmt.Sprintf("%s|%s", bet.Type, strings.Join(parts, ","))
 	}
-	return fmt.Sprintf("%s|%.1f|%.2f|%.2f", bet.Type, bet.Cut, bet.Plus, bet.Moins)
+	return fmt.Sprintf("%s|%.1f|%.2f|%.2f", bet.Type, bet.Cut, roundCote(bet.Plus), roundCote(bet.Moins))
 }
 
 // Check if two bets are effectively the same (same type and similar options)
@@ -150,9 +221,9 @@ func betsAreEqual(prev, curr Bet) bool {
 	return true
 }
 
-// Load previous data from winamax_aces_past.json
-func loadPreviousData() []MatchData {
-	data, err := os.ReadFile("winamax_aces_past.json")
+// Load previous data from the past file (winamax_aces_past.json for aces)
+func loadPreviousData(path string) []MatchData {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		// File doesn't exist yet, return empty
 		return nil
@@ -160,113 +231,12 @@ func loadPreviousData() []MatchData {
 
 	var previousData []MatchData
 	if err := json.Unmarshal(data, &previousData); err != nil {
-		log.Println("Error parsing winamax_aces_past.json:", err)
+		slog.Error("Error parsing past data", "file", path, "err", err)
 		return nil
 	}
 	return previousData
 }
 
-// Notification history file to track sent notifications
-const NOTIFICATION_HISTORY_FILE = "sent_notifications.txt"
-const NOTIFICATION_EXPIRY_DAYS = 7 // Entries older than this are removed
-
-// Load notification history - returns map of joueurs -> timestamp
-// Key is now joueurs (player names) to avoid issues with incorrect/changing links
-func loadNotificationHistory() map[string]time.Time {
-	history := make(map[string]time.Time)
-
-	data, err := os.ReadFile(NOTIFICATION_HISTORY_FILE)
-	if err != nil {
-		return history // File doesn't exist yet
-	}
-
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		// Format: timestamp|joueurs|matchLink
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) >= 2 {
-			timestamp, err := time.Parse(time.RFC3339, parts[0])
-			if err != nil {
-				continue
-			}
-			joueurs := parts[1] // Key is now joueurs
-			history[joueurs] = timestamp
-		}
-	}
-	return history
-}
-
-// Save notification history with joueurs and link - cleans up old entries
-// Format: timestamp|joueurs|matchLink (joueurs is the key, link is for reference)
-func saveNotificationHistoryWithLink(history map[string]time.Time, joueursToLink map[string]string) {
-	var lines []string
-	now := time.Now()
-
-	for joueurs, timestamp := range history {
-		// Skip entries older than NOTIFICATION_EXPIRY_DAYS
-		if now.Sub(timestamp).Hours() > float64(NOTIFICATION_EXPIRY_DAYS*24) {
-			continue
-		}
-		link := joueursToLink[joueurs]
-		if link == "" {
-			link = "unknown"
-		}
-		lines = append(lines, fmt.Sprintf("%s|%s|%s", timestamp.Format(time.RFC3339), joueurs, link))
-	}
-
-	if err := os.WriteFile(NOTIFICATION_HISTORY_FILE, []byte(strings.Join(lines, "\n")), 0644); err != nil {
-		log.Println("Error writing notification history:", err)
-	}
-}
-
-// Check if notification was already sent for this match (by joueurs)
-func wasNotificationSent(joueurs string) bool {
-	history := loadNotificationHistory()
-	if timestamp, exists := history[joueurs]; exists {
-		// Check if entry is still valid (not expired)
-		if time.Since(timestamp).Hours() <= float64(NOTIFICATION_EXPIRY_DAYS*24) {
-			return true
-		}
-	}
-	return false
-}
-
-// Load joueurs to link mapping from existing file
-func loadJoueursToLinkMapping() map[string]string {
-	mapping := make(map[string]string)
-	data, err := os.ReadFile(NOTIFICATION_HISTORY_FILE)
-	if err != nil {
-		return mapping
-	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) >= 3 {
-			joueurs := parts[1]
-			link := parts[2]
-			mapping[joueurs] = link
-		}
-	}
-	return mapping
-}
-
-// Mark notification as sent for this match (by joueurs, with link for reference)
-func markNotificationSent(joueurs, matchLink string) {
-	history := loadNotificationHistory()
-	joueursToLink := loadJoueursToLinkMapping()
-	history[joueurs] = time.Now()
-	joueursToLink[joueurs] = matchLink
-	saveNotificationHistoryWithLink(history, joueursToLink)
-}
-
 // Build a map of matchLink -> betType -> Bet for quick lookup
 func buildBetMap(data []MatchData) map[string]map[string]Bet {
 	result := make(map[string]map[string]Bet)
@@ -284,14 +254,14 @@ func buildBetMap(data []MatchData) map[string]map[string]Bet {
 
 // Compare current data with previous and send notifications for new bets
 // ONLY notifies for FIRST APPEARANCE of a bet type - ignores cote/cut changes
-// Uses notification history to avoid duplicate notifications for same match
-func compareAndNotify(currentData []MatchData) {
-	previousData := loadPreviousData()
+// Uses the family's notification history to avoid duplicate notifications for same match
+func compareAndNotify(family MarketFamily, currentData []MatchData, lg *slog.Logger) {
+	previousData := loadPreviousData(family.pastFile())
 	previousMap := buildBetMap(previousData)
 
 	for _, matchData := range currentData {
-		// Skip if we already sent notification for this match (check by joueurs to avoid link issues)
-		if wasNotificationSent(matchData.Match.Joueurs) {
+		// Skip if we already sent notification for this match
+		if wasNotificationSent(family, matchData.Match) {
 			continue
 		}
 
@@ -313,82 +283,59 @@ func compareAndNotify(currentData []MatchData) {
 			}
 		}
 
-		// Send notification if there are new bets
-		if len(newBets) > 0 {
-			notifyNewBetsGrouped(matchData.Match, newBets)
-			// Mark this match as notified (by joueurs, with link for reference)
-			markNotificationSent(matchData.Match.Joueurs, matchData.Match.Lien)
+		// Only what the channel's filter rules let through; the match stays
+		// unmarked when nothing does, so later bets can still be notified
+		newBets = alertRules.filterNew(family, matchData.Match, newBets)
+
+		// Send notification if there are new bets, and mark this match as
+		// notified unless the re-alert cap held it back
+		if len(newBets) > 0 && notifyNewBetsGrouped(family, matchData.Match, newBets, lg) {
+			markNotificationSent(family, matchData.Match)
 		}
 	}
 }
 
 // Save current data as past for next comparison
-func saveAsPast() {
-	// Copy winamax_aces.json to winamax_aces_past.json
-	data, err := os.ReadFile("winamax_aces.json")
+func saveAsPast(family MarketFamily) {
+	// Copy winamax_aces.json to winamax_aces_past.json (or the family's own files)
+	data, err := os.ReadFile(family.currentFile())
 	if err != nil {
-		log.Println("Error reading winamax_aces.json for copy:", err)
+		slog.Error("Error reading current data for copy", "file", family.currentFile(), "err", err)
 		return
 	}
-	if err := os.WriteFile("winamax_aces_past.json", data, 0644); err != nil {
-		log.Println("Error writing winamax_aces_past.json:", err)
+	if err := os.WriteFile(family.pastFile(), data, 0644); err != nil {
+		slog.Error("Error writing past data", "file", family.pastFile(), "err", err)
 	}
 }
 
-// Send ONE grouped notification for all new bets in a match
-// Order: 1) Plus/Moins individual players, 2) Plus/Moins match total, 3) Paliers individual players, 4) Paliers match total
-func notifyNewBetsGrouped(match Match, bets []Bet) {
-	var message strings.Builder
-
-	// Add match title (player names) as header so users know which match even if link changes
-	message.WriteString(fmt.Sprintf("🎾 <b>%s</b>\n\n", match.Joueurs))
-
-	// 1. Plus/Moins for individual players (contain "de" but not "(paliers)")
-	for _, bet := range bets {
-		if len(bet.Options) == 0 && strings.Contains(bet.Type, " de ") {
-			message.WriteString(fmt.Sprintf("<b>%s</b>  + %.1f @ %.2f / - %.1f @ %.2f\n\n",
-				bet.Type, bet.Cut, bet.Plus, bet.Cut, bet.Moins))
-		}
-	}
-
-	// 2. Plus/Moins match total (no "de" and no "(paliers)")
-	for _, bet := range bets {
-		if len(bet.Options) == 0 && !strings.Contains(bet.Type, " de ") {
-			message.WriteString(fmt.Sprintf("<b>%s</b>  + %.1f @ %.2f / - %.1f @ %.2f\n\n",
-				bet.Type, bet.Cut, bet.Plus, bet.Cut, bet.Moins))
-		}
+// Send ONE grouped notification for all new bets in a match, laid out by the
+// channel's template (see templates.go). The built-in one orders bets as
+// 1) Plus/Moins individual players, 2) Plus/Moins match total, 3) Paliers
+// individual players, 4) Paliers match total, 5) other templates. Returns
+// false when the channel's re-alert cap held it back.
+func notifyNewBetsGrouped(family MarketFamily, match Match, bets []Bet, lg *slog.Logger) bool {
+	if preferences.muted(family.Channel, match) {
+		lg.Debug("Match muted in channel, no notification", "match", match.Joueurs, "channel", family.Channel)
+		return true
 	}
-
-	// 3. Paliers for individual players (contain " - " with player name)
-	for _, bet := range bets {
-		if len(bet.Options) > 0 && strings.Contains(bet.Type, " - ") && !strings.HasSuffix(bet.Type, "(paliers)") {
-			message.WriteString(fmt.Sprintf("<b>%s</b> :\n", bet.Type))
-			for _, opt := range bet.Options {
-				message.WriteString(fmt.Sprintf("%.0f @ %.2f\n", opt.Seuil, opt.Cote))
-			}
-			message.WriteString("\n")
-		}
+	message, parseMode, err := notificationFormats.render(family.Channel, newNotificationView(family, match, bets))
+	if message == "" {
+		lg.Error("Failed to render new bet notification", "match", match.Joueurs, "err", err)
+		return true
 	}
-
-	// 4. Paliers match total (ends with just "(paliers)" without player name dash)
-	for _, bet := range bets {
-		if len(bet.Options) > 0 && !strings.Contains(bet.Type, " - ") {
-			message.WriteString(fmt.Sprintf("<b>%s</b> :\n", bet.Type))
-			for _, opt := range bet.Options {
-				message.WriteString(fmt.Sprintf("%.0f @ %.2f\n", opt.Seuil, opt.Cote))
-			}
-			message.WriteString("\n")
-		}
+	if err != nil {
+		lg.Warn("Notification template failed, sent with the built-in one", "channel", family.Channel, "err", err)
 	}
 
-	// Add link at the end
-	message.WriteString(fmt.Sprintf("🔗 <a href=\"%s\">LIEN</a>", match.Lien))
-
-	if err := sendTelegramMessage(NEW_BETS_CHANNEL, message.String()); err != nil {
-		log.Println("Failed to send new bet notification:", err)
-	} else {
-		log.Printf("Notification sent for new bets: %s (%d bets)\n", match.Joueurs, len(bets))
+	outcome, err := deliverAlert(lg, family.Channel, "nouveaux paris", match, message, parseMode, alertKeyboard(match))
+	if err != nil {
+		lg.Error("Failed to send new bet notification", "match", match.Joueurs, "err", err)
+	} else if outcome == DELIVERY_SENT {
+		lg.Info("Notification sent for new bets", "match", match.Joueurs, "bets", len(bets))
+		digest.notificationSent()
+		clv.track(family, match, bets, "notification")
 	}
+	return outcome != DELIVERY_HELD_BACK
 }
 
 // Sort bets in correct order:
@@ -398,49 +345,36 @@ func notifyNewBetsGrouped(match Match, bets []Bet) {
 // 4. Paliers Player 1
 // 5. Paliers Player 2
 // 6. Paliers Match Total
-func sortBets(bets []Bet, matchTitle string) []Bet {
-	// Extract player names from match title (format: "Player1 - Player2")
-	players := strings.Split(matchTitle, " - ")
-	player1Initial := ""
-	player2Initial := ""
-
-	if len(players) >= 2 {
-		// Get last name initial for matching (e.g., "F. Auger-Aliassime" -> "F. Auger-Aliassime")
-		player1Parts := strings.Fields(players[0])
-		player2Parts := strings.Fields(players[1])
-
-		if len(player1Parts) > 0 {
-			// Get initial letter of first name + last name initial
-			player1Initial = string(player1Parts[0][0]) + "."
-		}
-		if len(player2Parts) > 0 {
-			player2Initial = string(player2Parts[0][0]) + "."
-		}
-	}
-
+// 7. Other templates (kept in their original order)
+func sortBets(bets []Bet, match Match) []Bet {
 	// Categorize bets
 	var plusMoinsPlayer1, plusMoinsPlayer2, plusMoinsMatch []Bet
 	var paliersPlayer1, paliersPlayer2, paliersMatch []Bet
+	var others []Bet
 
 	for _, bet := range bets {
-		isPaliers := len(bet.Options) > 0
-		isPlayer1 := strings.Contains(bet.Type, player1Initial) || containsPlayerName(bet.Type, players, 0)
-		isPlayer2 := strings.Contains(bet.Type, player2Initial) || containsPlayerName(bet.Type, players, 1)
+		if bet.isOutcomeList() {
+			others = append(others, bet)
+			continue
+		}
 
-		if isPaliers {
-			if isPlayer1 && !isPlayer2 {
+		side := betSide(bet, match)
+		if bet.isLadder() {
+			switch side {
+			case 1:
 				paliersPlayer1 = append(paliersPlayer1, bet)
-			} else if isPlayer2 && !isPlayer1 {
+			case 2:
 				paliersPlayer2 = append(paliersPlayer2, bet)
-			} else {
+			default:
 				paliersMatch = append(paliersMatch, bet)
 			}
 		} else {
-			if isPlayer1 && !isPlayer2 {
+			switch side {
+			case 1:
 				plusMoinsPlayer1 = append(plusMoinsPlayer1, bet)
-			} else if isPlayer2 && !isPlayer1 {
+			case 2:
 				plusMoinsPlayer2 = append(plusMoinsPlayer2, bet)
-			} else {
+			default:
 				plusMoinsMatch = append(plusMoinsMatch, bet)
 			}
 		}
@@ -454,307 +388,30 @@ func sortBets(bets []Bet, matchTitle string) []Bet {
 	result = append(result, paliersPlayer1...)
 	result = append(result, paliersPlayer2...)
 	result = append(result, paliersMatch...)
+	result = append(result, others...)
 
 	return result
 }
 
-// Helper function to check if bet type contains a player name
-func containsPlayerName(betType string, players []string, playerIndex int) bool {
-	if playerIndex >= len(players) {
-		return false
-	}
-
-	playerName := players[playerIndex]
-	// Check for last name
-	parts := strings.Fields(playerName)
-	if len(parts) > 0 {
-		lastName := parts[len(parts)-1]
-		if strings.Contains(betType, lastName) {
-			return true
+// Which player a bet belongs to: 1 or 2, or 0 for the whole match. Bets linked
+// to a competitor when fetched use its ID, older saved bets are matched on names.
+func betSide(bet Bet, match Match) int {
+	if bet.CompetitorID != 0 {
+		switch bet.CompetitorID {
+		case match.Competitor1ID:
+			return 1
+		case match.Competitor2ID:
+			return 2
 		}
 	}
-	return false
-}
-
-func getInitialCookies(client tls_client.HttpClient) error {
-	url := URL_BASE + FrenchPage
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return err
-	}
-
-	hostName := extractHostname(URL_BASE)
-	req.Header = http.Header{
-		"Host":                      {hostName},
-		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
-		"Sec-Ch-Ua-Mobile":          {"?0"},
-		"Sec-Ch-Ua-Platform":        {`"Windows"`},
-		"Upgrade-Insecure-Requests": {"1"},
-		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
-		"Sec-Fetch-Site":            {"none"},
-		"Sec-Fetch-Mode":            {"navigate"},
-		"Sec-Fetch-User":            {"?1"},
-		"Sec-Fetch-Dest":            {"document"},
-		"Accept-Encoding":           {"gzip, deflate, br"},
-		"Accept-Language":           {"fr-FR,fr;q=0.9"},
-		http.HeaderOrderKey: {
-			"Host",
-			"Sec-Ch-Ua",
-			"Sec-Ch-Ua-Mobile",
-			"Sec-Ch-Ua-Platform",
-			"Upgrade-Insecure-Requests",
-			"User-Agent",
-			"Accept",
-			"Sec-Fetch-Site",
-			"Sec-Fetch-Mode",
-			"Sec-Fetch-User",
-			"Sec-Fetch-Dest",
-			"Accept-Encoding",
-			"Accept-Language",
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	fmt.Println("Initial GET status code:", resp.StatusCode)
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("initial request failed with status: %d", resp.StatusCode)
-	}
-	return nil
-}
-
-func getSessionID(client tls_client.HttpClient) (string, error) {
-	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
-	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=FR&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s", SocketURL, t)
-	req, _ := http.NewRequest(http.MethodGet, url, nil)
-
-	hostName := extractHostname(URL_BASE)
-	req.Header = http.Header{
-		"Host":                      {hostName},
-		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
-		"Sec-Ch-Ua-Mobile":          {"?0"},
-		"Sec-Ch-Ua-Platform":        {`"Windows"`},
-		"Upgrade-Insecure-Requests": {"1"},
-		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
-		"Sec-Fetch-Site":            {"none"},
-		"Sec-Fetch-Mode":            {"navigate"},
-		"Sec-Fetch-User":            {"?1"},
-		"Sec-Fetch-Dest":            {"document"},
-		"Accept-Encoding":           {"gzip, deflate, br"},
-		"Accept-Language":           {"fr-FR,fr;q=0.9"},
-		http.HeaderOrderKey: {
-			"Host",
-			"Sec-Ch-Ua",
-			"Sec-Ch-Ua-Mobile",
-			"Sec-Ch-Ua-Platform",
-			"Upgrade-Insecure-Requests",
-			"User-Agent",
-			"Accept",
-			"Sec-Fetch-Site",
-			"Sec-Fetch-Mode",
-			"Sec-Fetch-User",
-			"Sec-Fetch-Dest",
-			"Accept-Encoding",
-			"Accept-Language",
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	re := regexp.MustCompile(`\d+:\d+({.*})`)
-	match := re.FindStringSubmatch(string(body))
-	if len(match) < 2 {
-		return "", fmt.Errorf("SID JSON not found in response")
-	}
-
-	var sidData struct {
-		SID string `json:"sid"`
-	}
-	if err := json.NewDecoder(strings.NewReader(match[1])).Decode(&sidData); err != nil {
-		return "", fmt.Errorf("failed to decode SID JSON: %w", err)
-	}
-
-	fmt.Println("Extracted SID:", sidData.SID)
-	return sidData.SID, nil
-}
-
-func getInitialDataWithSID(client tls_client.HttpClient, sid string) ([]byte, error) {
-	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
-	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=FR&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", SocketURL, t, sid)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	hostName := extractHostname(URL_BASE)
-	req.Header = http.Header{
-		"Host":                      {hostName},
-		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
-		"Sec-Ch-Ua-Mobile":          {"?0"},
-		"Sec-Ch-Ua-Platform":        {`"Windows"`},
-		"Upgrade-Insecure-Requests": {"1"},
-		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
-		"Sec-Fetch-Site":            {"none"},
-		"Sec-Fetch-Mode":            {"navigate"},
-		"Sec-Fetch-User":            {"?1"},
-		"Sec-Fetch-Dest":            {"document"},
-		"Accept-Encoding":           {"gzip, deflate, br"},
-		"Accept-Language":           {"fr-FR,fr;q=0.9"},
-		http.HeaderOrderKey: {
-			"Host",
-			"Sec-Ch-Ua",
-			"Sec-Ch-Ua-Mobile",
-			"Sec-Ch-Ua-Platform",
-			"Upgrade-Insecure-Requests",
-			"User-Agent",
-			"Accept",
-			"Sec-Fetch-Site",
-			"Sec-Fetch-Mode",
-			"Sec-Fetch-User",
-			"Sec-Fetch-Dest",
-			"Accept-Encoding",
-			"Accept-Language",
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	return io.ReadAll(resp.Body)
-}
-
-func postSubscription(client tls_client.HttpClient, sid, payload string) error {
-	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
-	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=FR&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", SocketURL, t, sid)
-
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(payload))
-	if err != nil {
-		return err
-	}
-
-	hostName := extractHostname(URL_BASE)
-	req.Header = http.Header{
-		"Host":                      {hostName},
-		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
-		"Sec-Ch-Ua-Mobile":          {"?0"},
-		"Sec-Ch-Ua-Platform":        {`"Windows"`},
-		"Upgrade-Insecure-Requests": {"1"},
-		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
-		"Sec-Fetch-Site":            {"none"},
-		"Sec-Fetch-Mode":            {"navigate"},
-		"Sec-Fetch-User":            {"?1"},
-		"Sec-Fetch-Dest":            {"document"},
-		"Accept-Encoding":           {"gzip, deflate, br"},
-		"Accept-Language":           {"en-US,fr;q=0.9"},
-		http.HeaderOrderKey: {
-			"Host",
-			"Sec-Ch-Ua",
-			"Sec-Ch-Ua-Mobile",
-			"Sec-Ch-Ua-Platform",
-			"Upgrade-Insecure-Requests",
-			"User-Agent",
-			"Accept",
-			"Sec-Fetch-Site",
-			"Sec-Fetch-Mode",
-			"Sec-Fetch-User",
-			"Sec-Fetch-Dest",
-			"Accept-Encoding",
-			"Accept-Language",
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	fmt.Println("POST subscription body:", string(body))
-	if !strings.Contains(string(body), "ok") {
-		if !strings.Contains(string(body), "matches") {
-			return fmt.Errorf("POST subscription failed")
-		}
-	}
-	return nil
-}
-
-func getFinalData(client tls_client.HttpClient, sid string) ([]byte, error) {
-	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
-	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=FR&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", SocketURL, t, sid)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	hostName := extractHostname(URL_BASE)
-	req.Header = http.Header{
-		"Host":                      {hostName},
-		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
-		"Sec-Ch-Ua-Mobile":          {"?0"},
-		"Sec-Ch-Ua-Platform":        {`"Windows"`},
-		"Upgrade-Insecure-Requests": {"1"},
-		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
-		"Sec-Fetch-Site":            {"none"},
-		"Sec-Fetch-Mode":            {"navigate"},
-		"Sec-Fetch-User":            {"?1"},
-		"Sec-Fetch-Dest":            {"document"},
-		"Accept-Encoding":           {"gzip, deflate, br"},
-		"Accept-Language":           {"fr-FR,fr;q=0.9"},
-		http.HeaderOrderKey: {
-			"Host",
-			"Sec-Ch-Ua",
-			"Sec-Ch-Ua-Mobile",
-			"Sec-Ch-Ua-Platform",
-			"Upgrade-Insecure-Requests",
-			"User-Agent",
-			"Accept",
-			"Sec-Fetch-Site",
-			"Sec-Fetch-Mode",
-			"Sec-Fetch-User",
-			"Sec-Fetch-Dest",
-			"Accept-Encoding",
-			"Accept-Language",
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return bodyBytes, nil
+	return players.side(bet.Type, match)
 }
 
 // Load all proxies from file and convert to URL format
 func loadProxies(path string) []string {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Println("Error reading proxy file:", err)
+		slog.Error("Error reading proxy file", "err", err)
 		return nil
 	}
 
@@ -811,7 +468,7 @@ func getProxyShortName(proxyURL string) string {
 // Random sleep between 1 and 5 seconds to humanize requests
 func randomSleep() {
 	delay := time.Duration(1000+rand.Intn(4000)) * time.Millisecond
-	log.Printf("Waiting %.1fs...\n", delay.Seconds())
+	slog.Debug("Waiting before next request", "delay", delay)
 	time.Sleep(delay)
 }
 
@@ -844,511 +501,90 @@ func extractHostname(urlStr string) string {
 }
 
 func main() {
-	// Load all proxies
-	proxies := loadProxies("proxy.txt")
-	if len(proxies) == 0 {
-		log.Println("No proxies loaded, running without proxy")
-	} else {
-		log.Printf("Loaded %d proxies\n", len(proxies))
+	cfg, cfgErr := loadConfig(CONFIG_FILE)
+	config = cfg
+	logger := setupLogger(config.Log, os.Stdout)
+	if cfgErr != nil {
+		logger.Error("Error parsing config file, using defaults", "file", CONFIG_FILE, "err", cfgErr)
 	}
 
-	sendLog(fmt.Sprintf("🚀 Démarrage scraper - %d proxies chargés", len(proxies)))
-
-	retryCount := 0
-	loopCount := 0
-	var currentProxy string
-
-	for {
-		loopCount++
-
-		// Get a random proxy for this iteration
-		currentProxy = getRandomProxy(proxies)
-		proxyName := getProxyShortName(currentProxy)
-
-		jar := tls_client.NewCookieJar()
-		options := []tls_client.HttpClientOption{
-			tls_client.WithTimeoutSeconds(30),
-			tls_client.WithClientProfile(profiles.Chrome_133),
-			tls_client.WithCookieJar(jar),
-		}
-
-		// Add proxy if available
-		if currentProxy != "" {
-			options = append(options, tls_client.WithProxyUrl(currentProxy))
-		}
-
-		client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
-		if err != nil {
-			log.Println("Client error:", err)
-			retryCount++
-			if retryCount >= 3 {
-				sendLog("❌ Échec 3 fois de suite, arrêt du scraper")
-				log.Fatalln("Failed 3 times in a row, exiting.")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		// get init page session cookies
-		if err := getInitialCookies(client); err != nil {
-			log.Printf("Failed to get initial cookies (%s): %v\n", proxyName, err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog(fmt.Sprintf("❌ Échec cookies [%s]", proxyName))
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-				sendLog(fmt.Sprintf("⚠️ Erreur proxy [%s] - changement...", proxyName))
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		sendLog(fmt.Sprintf("🔄 Cycle #%d démarré [%s]", loopCount, proxyName))
-
-		// get socketio session id
-		sid, err := getSessionID(client)
-		if err != nil {
-			log.Println("Failed to get SID:", err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog("❌ Échec SID 3 fois de suite, arrêt du scraper")
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-				sendLog(fmt.Sprintf("⚠️ Erreur proxy SID [%s]", proxyName))
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		// do first request and get 2:40 back
-		if _, err := getInitialDataWithSID(client, sid); err != nil {
-			log.Println("Failed to get initial data:", err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog("❌ Échec données initiales 3 fois de suite, arrêt du scraper")
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		requestId := uuid.New().String()
-
-		// First data
-		postData := fmt.Sprintf("78:42[\"m\",{\"route\":\"sport:5\",\"requestId\":\"%s\"}]", requestId)
-
-		if err := postSubscription(client, sid, postData); err != nil {
-			log.Println("POST subscription failed:", err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog("❌ Échec subscription 3 fois de suite, arrêt du scraper")
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		finalData, err := getFinalData(client, sid)
-		if err != nil {
-			log.Println("Error fetching final data:", err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog("❌ Échec données finales 3 fois de suite, arrêt du scraper")
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-		fmt.Println("Got first data")
-		_ = finalData
-
-		clientTime := time.Now().UnixMilli()
-		postData = fmt.Sprintf(`129:42["m",{"requestId":"%s","route":"sport:5","data":true,"menu":true,"clientTime":%d}]`, requestId, clientTime)
-
-		// Data with categories
-		if err := postSubscription(client, sid, postData); err != nil {
-			log.Println("POST subscription failed:", err)
-			if !isProxyError(err) {
-				retryCount++
-				if retryCount >= 3 {
-					sendLog("❌ Échec subscription catégories 3 fois de suite, arrêt du scraper")
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-			} else {
-				log.Println("⚠️ Proxy error, trying another proxy...")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		finalData, err = getFinalData(client, sid)
-		if err != nil {
-			log.Println("Error fetching final data:", err)
-			retryCount++
-			if retryCount >= 3 {
-				log.Fatalln("Failed 3 times in a row, exiting.")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		if err := os.WriteFile("final_response.txt", finalData, 0644); err != nil {
-			log.Println("Error writing response to file:", err)
-			retryCount++
-			if retryCount >= 3 {
-				log.Fatalln("Failed 3 times in a row, exiting.")
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		// Filter Matches
-		jsonStr := string(finalData)
-		jsonStr = strings.TrimSpace(jsonStr)
-		if strings.HasPrefix(jsonStr, "//") {
-			jsonStr = jsonStr[strings.Index(jsonStr, "\n")+1:]
-		}
-
-		// Remove numeric prefix before every ["m", and any ] before that
-		re := regexp.MustCompile(`\](\d+:)?\["m",`)
-		jsonStr = re.ReplaceAllString(jsonStr, `,["m",`)
-
-		// Remove numeric prefix at the start (if any)
-		if idx := strings.Index(jsonStr, "["); idx > 0 {
-			jsonStr = jsonStr[idx:]
-		}
-
-		jsonStr = strings.TrimSpace(jsonStr)
-		if !strings.HasSuffix(jsonStr, "]]") {
-			jsonStr += "]]"
-		}
-
-		jsonStr, err = jsonrepair.RepairJSON(jsonStr)
-		if err != nil {
-			log.Println("Failed to repair JSON:", err)
-			sendLog("⚠️ Erreur réparation JSON - retry...")
-			continue
-		}
-
-		var arr []interface{}
-		if err := json.Unmarshal([]byte(jsonStr), &arr); err != nil {
-			log.Println("JSON parse error:", err)
-			sendLog("⚠️ Erreur parsing JSON - retry...")
-			continue
-		}
-		if len(arr) < 2 {
-			log.Println("Unexpected JSON structure, less than 2 elements")
-			sendLog("⚠️ Structure JSON inattendue - retry...")
-			continue
-		}
-		root, ok := arr[1].(map[string]interface{})
-		if !ok {
-			log.Println("Root element is not a map")
-			sendLog("⚠️ Format réponse invalide - retry...")
-			continue
-		}
-
-		matches, _ := root["matches"].(map[string]interface{})
-		if matches == nil {
-			log.Println("No matches found in the response")
-			sendLog("⚠️ Pas de 'matches' dans la réponse - retry...")
-			continue
-		}
-
-		// loop all matches - get filters key, and check if 548 is in there
-		var matchIDs []float64
-		for _, m := range matches {
-			match, _ := m.(map[string]interface{})
-			rawFilters, ok := match["filters"]
-			if !ok {
-				log.Println("No filters found for a match")
-				continue
-			}
-
-			filterList, ok := rawFilters.([]interface{})
-			if !ok {
-				log.Println("Filters are not in expected format")
-				continue
-			}
-
-			found := false
-			for _, f := range filterList {
-				filterVal, ok := f.(float64) // JSON numbers are float64 by default
-				if ok && int(filterVal) == 548 {
-					found = true
-					matchID, _ := match["matchId"].(float64)
-					if matchID != 0 {
-						matchIDs = append(matchIDs, matchID)
-					} else {
-						log.Println("Match ID is empty for a match with filter 548")
-					}
-					break
-				}
-			}
-
-			if !found {
-				continue
-			}
-		}
-
-		fmt.Println("Matches found - Aces:", len(matchIDs))
-		if len(matchIDs) > 0 {
-			sendLog(fmt.Sprintf("🎾 %d match(s) avec aces trouvé(s)", len(matchIDs)))
+	// Load all proxies, per book
+	bookProxies := make(map[string][]string)
+	totalProxies := 0
+	for _, book := range config.Books {
+		proxies := loadProxies(book.Proxies)
+		if len(proxies) == 0 {
+			logger.Warn("No proxies loaded, running without proxy", "book", book.Name, "file", book.Proxies)
 		}
+		bookProxies[book.Name] = proxies
+		totalProxies += len(proxies)
+	}
 
-		// Fetch Data for each match
-		var allResults []MatchData
-		for _, matchID := range matchIDs {
-			matchIDStr := fmt.Sprintf("%.0f", matchID)
-
-			payload := fmt.Sprintf(`85:42["m",{"route":"match:%s","data":true,"menu":true,"clientTime":%s}]`, matchIDStr, fmt.Sprintf("%d", time.Now().UnixMilli()))
-			if err := postSubscription(client, sid, payload); err != nil {
-				log.Println("POST subscription for match failed:", err)
-				if !isProxyError(err) {
-					retryCount++
-				} else {
-					log.Println("⚠️ Proxy error on match request, skipping...")
-				}
-				continue
-			}
-
-			finalData, err = getFinalData(client, sid)
-			if err != nil {
-				log.Println("Error fetching final data for match:", err)
-				retryCount++
-				continue
-			}
-
-			// extract data
-			jsonStr := string(finalData)
-			jsonStr = strings.TrimSpace(jsonStr)
-			if strings.HasPrefix(jsonStr, "//") {
-				jsonStr = jsonStr[strings.Index(jsonStr, "\n")+1:]
-			}
-
-			// Remove numeric prefix before every ["m", and any ] before that
-			re := regexp.MustCompile(`\]?\d+:\d+\["m",`)
-			jsonStr = re.ReplaceAllString(jsonStr, `,["m",`)
-
-			// Remove numeric prefix at the start (if any)
-			if idx := strings.Index(jsonStr, "["); idx > 0 {
-				jsonStr = jsonStr[idx:]
-			}
-
-			jsonStr = strings.TrimSpace(jsonStr)
-			if !strings.HasSuffix(jsonStr, "]]") {
-				jsonStr += "]]"
-			}
-
-			jsonStr, err = jsonrepair.RepairJSON(jsonStr)
-			if err != nil {
-				log.Println("Failed to repair JSON:", err)
-				continue
-			}
-
-			if err := os.WriteFile("match_response.txt", []byte(jsonStr), 0644); err != nil {
-				log.Println("Error writing response to file:", err)
-				retryCount++
-				if retryCount >= 3 {
-					log.Fatalln("Failed 3 times in a row, exiting.")
-				}
-				time.Sleep(10 * time.Second)
-				continue
-			}
-
-			var arr []interface{}
-			if err := json.Unmarshal([]byte(jsonStr), &arr); err != nil {
-				fmt.Println("Failed to unmarshal JSON:", err)
-				continue
-			}
-			if len(arr) < 2 {
-				fmt.Println("Unexpected JSON structure, expected at least 2 elements")
-				continue
-			}
-
-			var bets map[string]interface{}
-			var ok bool
-
-			bets, outcomes, odds, ok := findLargestBetBlock(arr)
-			if !ok {
-				log.Println("No complete bet block found in the response")
-				retryCount++
-				continue
-			}
-			fmt.Println("Bets found:", len(bets))
-
-			var finalBets []Bet
-			for _, b := range bets {
-				if b == nil {
-					continue
-				}
-				bet, ok := b.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				// check if contains nombre d'aces
-				betTypeName, _ := bet["betTypeName"].(string)
-				if !strings.Contains(strings.ToLower(betTypeName), "nombre d'aces") {
-					continue
-				}
-
-				rawOutcomes, ok := bet["outcomes"].([]interface{})
-				if !ok {
-					log.Printf("dynamic bet %q has no outcomes\n", betTypeName)
-					continue
-				}
-
-				// Bet Type
-				template, _ := bet["template"].(string)
-
-				if template == "dynamic" {
-					var opts []Option
-					for _, id := range rawOutcomes {
-						key := fmt.Sprintf("%.0f", id.(float64)) // map keys are strings
-						if oc, ok := outcomes[key].(map[string]interface{}); ok {
-							lbl, _ := oc["label"].(string)
-							seuil, _ := strconv.ParseFloat(regexp.MustCompile(`[\d.]+`).FindString(lbl), 64)
-
-							if cote, ok := odds[key].(float64); ok {
-								cote = math.Round(cote*20) / 20
-								opts = append(opts, Option{Seuil: seuil, Cote: cote})
-							}
-						}
-					}
-
-					finalBets = append(finalBets, Bet{
-						Type:    betTypeName,
-						Options: opts,
-					})
-					continue
-				}
-
-				total := strings.TrimPrefix(bet["specialBetValue"].(string), "total=")
-				cut, _ := strconv.ParseFloat(total, 64)
-				overKey := fmt.Sprintf("%.0f", rawOutcomes[0].(float64))
-				underKey := fmt.Sprintf("%.0f", rawOutcomes[1].(float64))
-
-				plus := odds[overKey].(float64)
-				moins := odds[underKey].(float64)
-				plus = math.Round(plus*20) / 20
-				moins = math.Round(moins*20) / 20
-
-				// save
-				finalBets = append(finalBets, Bet{
-					Type:  betTypeName,
-					Cut:   cut,
-					Plus:  plus,
-					Moins: moins,
-				})
-			}
-
-			// save match
-			matchTitle := ""
-			if matchObj, ok := matches[matchIDStr].(map[string]interface{}); ok {
-				matchTitle, _ = matchObj["title"].(string)
-			}
-
-			link := fmt.Sprintf("https://www.winamax.fr/paris-sportifs/match/%s", matchIDStr)
-
-			// Sort bets in correct order based on player names from match title
-			sortedBets := sortBets(finalBets, matchTitle)
-
-			matchData := MatchData{
-				Match: Match{
-					Joueurs: matchTitle,
-					Lien:    link,
-				},
-				Bet: sortedBets,
-			}
-
-			allResults = append(allResults, matchData)
-
-		}
-
-		// save matches
-		output, err := json.MarshalIndent(allResults, "", "  ")
-		if err != nil {
-			log.Fatalln("Failed to marshal final output:", err)
-		}
-		if err := os.WriteFile(CURRENT_FILE, output, 0644); err != nil {
-			log.Fatalln("Failed to write output JSON:", err)
-		}
-
-		fmt.Println("Saved aces bets to", CURRENT_FILE)
-
-		// Compare with previous data and notify for new bets
-		compareAndNotify(allResults)
+	if config.Live.Enabled && config.Live.Channel == "" {
+		logger.Warn("Live mode needs its own channel, disabled")
+		config.Live.Enabled = false
+	}
 
-		// Save current as past for next iteration
-		saveAsPast()
+	notificationFormats.load(config.Notifications, logger)
+	if rules, err := compileRules(config.AlertRules); err != nil {
+		logger.Error("Invalid alert rule, no rules in use", "err", err)
+	} else {
+		alertRules.set(rules)
+	}
+	if config.AlertRules.ReloadSeconds > 0 {
+		go runRulesReload(CONFIG_FILE, time.Duration(config.AlertRules.ReloadSeconds)*time.Second, logger.With("stage", "rules"))
+	}
 
-		// Reset retry count on success
-		retryCount = 0
+	if err := delivery.load(); err != nil {
+		logger.Error("Error loading delivery state", "file", DELIVERY_FILE, "err", err)
+	}
+	go runQuietSummaries(logger.With("stage", "delivery"))
+	if err := preferences.load(); err != nil {
+		logger.Error("Error loading preferences", "file", PREFERENCES_FILE, "err", err)
+	}
+	if err := players.load(); err != nil {
+		logger.Error("Error loading player registry", "file", PLAYERS_FILE, "err", err)
+	}
+	if err := clv.load(); err != nil {
+		logger.Error("Error loading CLV tracking", "file", CLV_FILE, "err", err)
+	}
+	go runCLVReport(LOG_CHANNEL, logger.With("stage", "clv"))
 
-		// Count total bets
-		totalBets := 0
-		for _, m := range allResults {
-			totalBets += len(m.Bet)
+	if config.Bot.Enabled {
+		if err := ledger.load(config.Bot.LedgerFile); err != nil {
+			logger.Error("Error loading bet ledger", "file", config.Bot.LedgerFile, "err", err)
 		}
-
-		// Send log for each check cycle with proxy info
-		timestamp := time.Now().Format("15:04:05")
-		sendLog(fmt.Sprintf("✅ [%s] OK - %d matchs, %d paris [%s]", timestamp, len(allResults), totalBets, proxyName))
-
-		time.Sleep(15 * time.Second)
+		go runBot(logger.With("stage", "bot"))
 	}
-}
 
-func findLargestBetBlock(node interface{}) (bets, outcomes, odds map[string]interface{}, found bool) {
-	var maxLen int
+	logger.Info(fmt.Sprintf("🚀 Démarrage scraper - %d proxies chargés, %d sport(s), %d site(s)", totalProxies, len(config.Sports), len(config.Books)), "proxies", totalProxies)
 
-	var walk func(interface{})
-	walk = func(n interface{}) {
-		switch v := n.(type) {
-		case map[string]interface{}:
-			if b, ok := v["bets"].(map[string]interface{}); ok {
-				if len(b) > maxLen {
-					if o, ok1 := v["outcomes"].(map[string]interface{}); ok1 {
-						if oddsMap, ok2 := v["odds"].(map[string]interface{}); ok2 {
-							// Save the biggest block with bets, outcomes, and odds
-							maxLen = len(b)
-							bets = b
-							outcomes = o
-							odds = oddsMap
-							found = true
-						}
-					}
-				}
-			}
-			for _, child := range v {
-				walk(child)
-			}
-		case []interface{}:
-			for _, child := range v {
-				walk(child)
+	// One watcher per sport and book, each with its own session and cycle
+	var wg sync.WaitGroup
+	n := 0
+	for _, sport := range config.Sports {
+		for _, book := range config.Books {
+			delay := time.Duration(n) * 3 * time.Second
+			n++
+			source := newWinamaxBook(sport, book, bookProxies[book.Name])
+			watcherLog := logger.With("sport", sport.Name, "book", book.Name)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Stagger the first requests so watchers don't hit Winamax at the same time
+				time.Sleep(delay)
+				runWatcher(source, watcherLog)
+			}()
+
+			// In-play alerts only come from books that notify
+			if config.Live.Enabled && !book.Silent {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					runLiveWatcher(source, watcherLog)
+				}()
 			}
 		}
 	}
-
-	walk(node)
-	return
+	wg.Wait()
 }
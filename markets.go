@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A family of markets (any template) that share an output file and a
+// notification channel, e.g. aces, games, breaks
+type MarketFamily struct {
+	Name     string   `json:"name"`      // used in file names: winamax_<name>.json
+	Keywords []string `json:"keywords"`  // lower-case substrings of betTypeName, first matching family wins
+	BetTypes []int    `json:"bet_types"` // Winamax betType IDs, the same on every book whatever the language
+	Filters  []int    `json:"filters"`   // sport page filters a match must carry (empty = every match), 548 = aces on tennis
+	Channel  string   `json:"channel"`   // Telegram chat for new bet notifications, families may share one
+	Doubles  string   `json:"doubles"`   // include (default), exclude or only: doubles matches in alerts
+
+	// Substrings of the tournament or category name, any case or accents: only matches
+	// of an included tournament (all when empty) that no exclusion names are fetched
+	Tournaments        []string `json:"tournaments"`
+	ExcludeTournaments []string `json:"exclude_tournaments"`
+
+	sport string // set from the SportConfig the family belongs to
+	emoji string
+	book  string // set for books other than winamax.fr
+}
+
+// Aces keep the historical file names so existing past/history files are reused
+func defaultMarkets() []MarketFamily {
+	return []MarketFamily{
+		{
+			Name:     "aces",
+			Keywords: []string{"nombre d'aces", "aces - résultat"},
+			// 5685 is "Aces - Résultat", who serves the more aces (3way)
+			BetTypes: []int{5681, 5682, 5683, 5685, 5994, 5995, 5996},
+			Filters:  []int{548},
+			Channel:  NEW_BETS_CHANNEL,
+		},
+	}
+}
+
+// Tennis families are named after the market only, other sports get the sport as
+// prefix and other books the book as suffix
+func (f MarketFamily) fileStem() string {
+	stem := f.Name
+	if f.sport != "" && f.sport != "tennis" {
+		stem = f.sport + "_" + stem
+	}
+	if f.book != "" {
+		stem += "_" + f.book
+	}
+	return stem
+}
+
+func (f MarketFamily) isTennisAces() bool {
+	return f.Name == "aces" && (f.sport == "" || f.sport == "tennis") && f.book == ""
+}
+
+// Book the family is bound to, "fr" for winamax.fr
+func (f MarketFamily) bookName() string {
+	if f.book == "" {
+		return "fr"
+	}
+	return f.book
+}
+
+func (f MarketFamily) currentFile() string {
+	if f.isTennisAces() {
+		return CURRENT_FILE
+	}
+	return fmt.Sprintf("winamax_%s.json", f.fileStem())
+}
+
+func (f MarketFamily) pastFile() string {
+	if f.isTennisAces() {
+		return PAST_FILE
+	}
+	return fmt.Sprintf("winamax_%s_past.json", f.fileStem())
+}
+
+func (f MarketFamily) historyFile() string {
+	if f.isTennisAces() {
+		return NOTIFICATION_HISTORY_FILE
+	}
+	return fmt.Sprintf("sent_notifications_%s.json", f.fileStem())
+}
+
+// Text history used before matchId keys, migrated once into historyFile
+func (f MarketFamily) legacyHistoryFile() string {
+	if f.isTennisAces() {
+		return LEGACY_NOTIFICATION_HISTORY_FILE
+	}
+	return fmt.Sprintf("sent_notifications_%s.txt", f.fileStem())
+}
+
+// Check whether a bet type belongs to this family
+func (f MarketFamily) matches(betTypeName string, betTypeID int) bool {
+	for _, id := range f.BetTypes {
+		if id == betTypeID {
+			return true
+		}
+	}
+	lower := strings.ToLower(betTypeName)
+	for _, keyword := range f.Keywords {
+		if strings.Contains(lower, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check whether a match from the sport page should be fetched for this family
+func (f MarketFamily) wantsMatch(filters []interface{}) bool {
+	if len(f.Filters) == 0 {
+		return true
+	}
+	for _, raw := range filters {
+		filterVal, ok := raw.(float64) // JSON numbers are float64 by default
+		if !ok {
+			continue
+		}
+		for _, wanted := range f.Filters {
+			if int(filterVal) == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Doubles settings of a family
+const (
+	DOUBLES_INCLUDE = "include"
+	DOUBLES_EXCLUDE = "exclude"
+	DOUBLES_ONLY    = "only"
+)
+
+// Whether alerts of this family cover a match, given its doubles setting
+func (f MarketFamily) alertsOn(m Match) bool {
+	switch f.Doubles {
+	case DOUBLES_EXCLUDE:
+		return !m.isDoubles()
+	case DOUBLES_ONLY:
+		return m.isDoubles()
+	}
+	return true
+}
+
+// Matches this family alerts on; the others are still saved and compared across books
+func (f MarketFamily) alertMatches(results []MatchData) []MatchData {
+	if f.Doubles == "" || f.Doubles == DOUBLES_INCLUDE {
+		return results
+	}
+	var kept []MatchData
+	for _, m := range results {
+		if f.alertsOn(m.Match) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// Check whether a match's tournament passes the family's include and exclude lists
+func (f MarketFamily) wantsTournament(m Match) bool {
+	name := foldName(m.Categorie + " " + m.Tournoi)
+	mentions := func(substrings []string) bool {
+		for _, substring := range substrings {
+			if strings.Contains(name, foldName(substring)) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(f.Tournaments) > 0 && !mentions(f.Tournaments) {
+		return false
+	}
+	return !mentions(f.ExcludeTournaments)
+}
+
+// Find the family a bet type belongs to
+func familyFor(families []MarketFamily, betTypeName string, betTypeID int) (MarketFamily, bool) {
+	for _, family := range families {
+		if family.matches(betTypeName, betTypeID) {
+			return family, true
+		}
+	}
+	return MarketFamily{}, false
+}
+
+// Parse a specialBetValue such as "setnr=1|total=8.5" into its parameters
+func parseSpecialBetValue(special string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(special, "|") {
+		key, value, ok := strings.Cut(part, "=")
+		if ok {
+			params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return params
+}
+
+var seuilRegexp = regexp.MustCompile(`[\d.]+`)
+
+// Round a cote to the nearest 0.05 like the Winamax display. Only for what is
+// shown or compared with what users see: bets keep the raw cotes, which margins
+// and fair prices are computed from.
+func roundCote(cote float64) float64 {
+	return math.Round(cote*20) / 20
+}
+
+// Winamax bet templates seen in the match payload
+const (
+	TEMPLATE_2WAY           = "2way"
+	TEMPLATE_3WAY           = "3way"
+	TEMPLATE_ASIAN_HANDICAP = "asian_handicap"
+	TEMPLATE_LIST           = "List"
+	TEMPLATE_LIST_ODD       = "ListOdd"
+	TEMPLATE_SCORE          = "Score"
+	TEMPLATE_SET_SCORE      = "SetScore"
+	TEMPLATE_OVER_UNDER     = "OverUnder"
+	TEMPLATE_DYNAMIC        = "dynamic"
+)
+
+// Ladder (paliers) bets have thresholds
+func (b Bet) isLadder() bool {
+	return len(b.Options) > 0
+}
+
+// Over/Under lines - bets saved before templates were recorded are either ladders or Over/Under
+func (b Bet) isOverUnder() bool {
+	return !b.isLadder() && (b.Template == TEMPLATE_OVER_UNDER || b.Template == "")
+}
+
+// Every other template is shown as its list of outcomes
+func (b Bet) isOutcomeList() bool {
+	return !b.isLadder() && !b.isOverUnder()
+}
+
+// Read the priced outcomes of a bet in payload order, skipping outcomes without odds
+func extractOutcomes(rawOutcomes []interface{}, outcomes, odds map[string]interface{}) []Outcome {
+	var result []Outcome
+	for _, id := range rawOutcomes {
+		idVal, ok := id.(float64)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%.0f", idVal) // map keys are strings
+		cote, ok := odds[key].(float64)
+		if !ok {
+			continue
+		}
+		oc, _ := outcomes[key].(map[string]interface{})
+		code, _ := oc["code"].(string)
+		lbl, _ := oc["label"].(string)
+		result = append(result, Outcome{Code: code, Label: lbl, Cote: cote})
+	}
+	return result
+}
+
+// Build a Bet from a raw Winamax bet for any template: dynamic becomes a ladder,
+// OverUnder a cut with plus/moins, asian_handicap keeps its handicap, and every
+// bet keeps its labelled outcomes. Returns an error when nothing is priced.
+func extractBet(bet map[string]interface{}, outcomes, odds map[string]interface{}) (Bet, error) {
+	betTypeName, _ := bet["betTypeName"].(string)
+	template, _ := bet["template"].(string)
+	special, _ := bet["specialBetValue"].(string)
+	params := parseSpecialBetValue(special)
+
+	rawOutcomes, ok := bet["outcomes"].([]interface{})
+	if !ok {
+		return Bet{}, fmt.Errorf("bet %q has no outcomes", betTypeName)
+	}
+
+	betTypeID, _ := bet["betType"].(float64)
+	result := Bet{
+		Type:      betTypeName,
+		BetTypeID: int(betTypeID),
+		Template:  template,
+		Outcomes:  extractOutcomes(rawOutcomes, outcomes, odds),
+	}
+	if len(result.Outcomes) == 0 {
+		return Bet{}, fmt.Errorf("bet %q (%s) has no priced outcomes", betTypeName, template)
+	}
+
+	switch template {
+	case TEMPLATE_DYNAMIC:
+		for _, oc := range result.Outcomes {
+			// Labels look like "10 ou plus", with a decimal comma when needed; one
+			// without a number isn't a threshold and is skipped rather than read as 0
+			seuil, err := strconv.ParseFloat(seuilRegexp.FindString(strings.ReplaceAll(oc.Label, ",", ".")), 64)
+			if err != nil {
+				continue
+			}
+			result.Options = append(result.Options, Option{Seuil: seuil, Cote: oc.Cote})
+		}
+		if len(result.Options) == 0 {
+			return Bet{}, fmt.Errorf("dynamic bet %q has no threshold in its labels", betTypeName)
+		}
+
+	case TEMPLATE_OVER_UNDER:
+		total, ok := params["total"]
+		if !ok {
+			return Bet{}, fmt.Errorf("over/under bet %q has no total (%q)", betTypeName, special)
+		}
+		cut, err := strconv.ParseFloat(total, 64)
+		if err != nil {
+			return Bet{}, fmt.Errorf("over/under bet %q has an invalid total: %w", betTypeName, err)
+		}
+		if len(result.Outcomes) != 2 {
+			return Bet{}, fmt.Errorf("over/under bet %q has %d priced outcomes", betTypeName, len(result.Outcomes))
+		}
+		result.Cut = cut
+
+		// Outcomes are normally [over, under] but trust the code or label when they say otherwise
+		// (codes are "over"/"under" for games but "12"/"13" for aces)
+		for i, oc := range result.Outcomes {
+			isOver := i == 0
+			switch {
+			case oc.Code == "over" || strings.HasPrefix(oc.Label, "Plus"):
+				isOver = true
+			case oc.Code == "under" || strings.HasPrefix(oc.Label, "Moins"):
+				isOver = false
+			}
+			if isOver {
+				result.Plus = oc.Cote
+			} else {
+				result.Moins = oc.Cote
+			}
+		}
+
+	case TEMPLATE_ASIAN_HANDICAP:
+		if hcp, ok := params["hcp"]; ok {
+			result.Handicap, _ = strconv.ParseFloat(hcp, 64)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Every competitor seen on a sport page with the names it was listed under
+const PLAYERS_FILE = "players.json"
+
+// A player (or a doubles pair) known by its Winamax competitor ID. Names keeps
+// every form seen - sport page name and title half - folded for comparison.
+type player struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`              // as last listed, for display
+	Members []string `json:"members,omitempty"` // the two players of a doubles pair
+	Names   []string `json:"names"`
+}
+
+type playerRegistry struct {
+	mu      sync.Mutex
+	path    string
+	players map[int64]*player
+	dirty   bool
+}
+
+var players = &playerRegistry{path: PLAYERS_FILE, players: make(map[int64]*player)}
+
+func (r *playerRegistry) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []*player
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for _, p := range list {
+		r.players[p.ID] = p
+	}
+	return nil
+}
+
+// Write the registry when names were added since the last save
+func (r *playerRegistry) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	list := make([]*player, 0, len(r.players))
+	for _, p := range r.players {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b *player) int { return strings.Compare(a.Name, b.Name) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+// Remember the names a competitor is listed under: the sport page name and its
+// half of the title ("P1 - P2", competitor 1 first)
+func (r *playerRegistry) record(id int64, names ...string) {
+	if id == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.players[id]
+	if !ok {
+		p = &player{ID: id}
+		r.players[id] = p
+		r.dirty = true
+	}
+	named := false
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !named && p.Name != name {
+			p.Name = name
+			r.dirty = true
+		}
+		named = true
+		if members := pairMembers(name); len(members) > 1 && !slices.Equal(p.Members, members) {
+			p.Members = members
+			r.dirty = true
+		}
+		if folded := normalizePlayerText(name); folded != "" && !slices.Contains(p.Names, folded) {
+			p.Names = append(p.Names, folded)
+			r.dirty = true
+		}
+	}
+}
+
+// Display name of a competitor, "" when unknown
+func (r *playerRegistry) name(id int64) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.players[id]; ok {
+		return p.Name
+	}
+	return ""
+}
+
+// Record both competitors of a sport page match
+func (r *playerRegistry) recordMatch(m Match, name1, name2 string) {
+	title1, title2, _ := strings.Cut(m.Joueurs, " - ")
+	r.record(m.Competitor1ID, name1, title1)
+	r.record(m.Competitor2ID, name2, title2)
+}
+
+// Known names of a competitor, plus its half of the title
+func (r *playerRegistry) names(id int64, titleHalf string) []string {
+	var names []string
+	if folded := normalizePlayerText(titleHalf); folded != "" {
+		names = append(names, folded)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.players[id]; ok && id != 0 {
+		names = append(names, p.Names...)
+	}
+	return names
+}
+
+// Which competitor a bet name refers to: 1 or 2, or 0 for the whole match.
+// Each side keeps the longest of its name forms found in the bet name; when
+// both are found, the side whose form contains the other's wins ("f. cerundolo"
+// over "cerundolo"), otherwise the bet names both players and is the match's.
+func (r *playerRegistry) side(betType string, m Match) int {
+	text := " " + normalizePlayerText(betType) + " "
+	title1, title2, _ := strings.Cut(m.Joueurs, " - ")
+	found1 := longestNameForm(text, r.names(m.Competitor1ID, title1))
+	found2 := longestNameForm(text, r.names(m.Competitor2ID, title2))
+	switch {
+	case found1 == "" && found2 == "":
+		return 0
+	case found2 == "":
+		return 1
+	case found1 == "":
+		return 2
+	case len(found1) > len(found2) && strings.Contains(found1, found2):
+		return 1
+	case len(found2) > len(found1) && strings.Contains(found2, found1):
+		return 2
+	}
+	return 0
+}
+
+// Set the competitor ID of every player bet, on a copy so cached bets are left alone
+func (r *playerRegistry) link(bets []Bet, m Match) []Bet {
+	linked := make([]Bet, len(bets))
+	for i, bet := range bets {
+		bet.CompetitorID = 0
+		switch r.side(bet.Type, m) {
+		case 1:
+			bet.CompetitorID = m.Competitor1ID
+		case 2:
+			bet.CompetitorID = m.Competitor2ID
+		}
+		linked[i] = bet
+	}
+	return linked
+}
+
+// Longest name form found as whole words in text (padded with spaces)
+func longestNameForm(text string, names []string) string {
+	best := ""
+	for _, name := range names {
+		// Each player of a doubles pair counts
+		for _, member := range pairMembers(name) {
+			for _, form := range nameForms(member) {
+				if len(form) > len(best) && strings.Contains(text, " "+form+" ") {
+					best = form
+				}
+			}
+		}
+	}
+	return best
+}
+
+// Ways a normalized name can be written in a bet name: in full, with the first
+// names abbreviated, and by its last names alone. Bare initials are never a
+// form on their own.
+func nameForms(name string) []string {
+	words := strings.Fields(name)
+	var initials, rest []string
+	for i, word := range words {
+		if !strings.HasSuffix(word, ".") {
+			rest = words[i:]
+			break
+		}
+		initials = append(initials, word)
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+
+	forms := []string{name}
+	for i := range rest {
+		last := strings.Join(rest[i:], " ")
+		forms = append(forms, last)
+		// "camilo ugo carabelli" is also "c. ugo carabelli" and "c. carabelli"
+		if i > 0 && len(initials) == 0 {
+			forms = append(forms, string([]rune(rest[0])[:1])+". "+last)
+		}
+		if len(initials) > 0 {
+			forms = append(forms, strings.Join(initials, " ")+" "+last)
+		}
+	}
+	return forms
+}
+
+var playerPunctuation = strings.NewReplacer("(", " ", ")", " ", ",", " ", ":", " ", ".", ". ", "/", " / ")
+
+// Folded name (see foldName) with initials spaced out as "c. ugo carabelli"
+// and punctuation removed, so bet names and titles compare word by word
+func normalizePlayerText(text string) string {
+	folded := foldName(playerPunctuation.Replace(text))
+	return strings.Join(strings.Fields(folded), " ")
+}
+
+// Players of one side of a title: one name, or the two of a doubles pair
+// written "A.Danilina/A.Krunic" (with or without spaces around the slash)
+func pairMembers(side string) []string {
+	var members []string
+	for _, member := range strings.Split(side, "/") {
+		if member = strings.TrimSpace(member); member != "" {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// Both sides of a "P1 - P2" title with their players
+func titleSides(title string) [][]string {
+	var sides [][]string
+	for _, side := range strings.Split(title, " - ") {
+		sides = append(sides, pairMembers(side))
+	}
+	return sides
+}
+
+// Doubles matches have a pair on each side
+func (m Match) isDoubles() bool {
+	sides := titleSides(m.Joueurs)
+	return len(sides) == 2 && len(sides[0]) > 1 && len(sides[1]) > 1
+}
+
+// Title for alert headers: doubles pairs get spaced slashes and a "(double)" tag
+func (m Match) displayTitle() string {
+	if !m.isDoubles() {
+		return m.Joueurs
+	}
+	var sides []string
+	for _, members := range titleSides(m.Joueurs) {
+		sides = append(sides, strings.Join(members, " / "))
+	}
+	return strings.Join(sides, " - ") + " (double)"
+}
+
+// Player a side-1 or side-2 bet is on. In doubles, the pair member named in
+// the bet when only one is, otherwise the whole pair; "" for match bets.
+func sidePlayer(betType, title string, side int) string {
+	sides := titleSides(title)
+	if side < 1 || side > len(sides) {
+		return ""
+	}
+	members := sides[side-1]
+	if len(members) > 1 {
+		text := " " + normalizePlayerText(betType) + " "
+		var named []string
+		for _, member := range members {
+			if longestNameForm(text, []string{normalizePlayerText(member)}) != "" {
+				named = append(named, member)
+			}
+		}
+		if len(named) == 1 {
+			return named[0]
+		}
+	}
+	return strings.Join(members, " / ")
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mutes and followed matches set with the alert buttons, per chat
+const PREFERENCES_FILE = "preferences.json"
+
+// A match whose cote movements are posted to a chat until it starts
+type trackedMatch struct {
+	Joueurs    string    `json:"joueurs"`
+	Lien       string    `json:"lien"`
+	MatchStart int64     `json:"matchStart,omitempty"`
+	By         string    `json:"by"`
+	Since      time.Time `json:"since"`
+}
+
+// Preferences of one chat: a channel or group, or a user in a private chat
+type chatPreferences struct {
+	MutedPlayers     map[int64]string        `json:"mutedPlayers,omitempty"`     // competitor ID -> name
+	MutedTournaments map[int64]string        `json:"mutedTournaments,omitempty"` // tournament ID -> name
+	Tracked          map[string]trackedMatch `json:"tracked,omitempty"`          // matchId -> match
+}
+
+type preferenceStore struct {
+	mu    sync.Mutex
+	path  string
+	chats map[string]*chatPreferences
+	// Cotes last posted for tracked matches: chat|matchId|family -> bet key -> signature
+	lastCotes map[string]map[string]string
+}
+
+var preferences = &preferenceStore{
+	path:      PREFERENCES_FILE,
+	chats:     make(map[string]*chatPreferences),
+	lastCotes: make(map[string]map[string]string),
+}
+
+func (p *preferenceStore) load() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &p.chats)
+}
+
+// Callers hold the lock
+func (p *preferenceStore) save() error {
+	data, err := json.MarshalIndent(p.chats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0644)
+}
+
+// Callers hold the lock
+func (p *preferenceStore) chat(chatID string) *chatPreferences {
+	prefs, ok := p.chats[chatID]
+	if !ok {
+		prefs = &chatPreferences{}
+		p.chats[chatID] = prefs
+	}
+	if prefs.MutedPlayers == nil {
+		prefs.MutedPlayers = make(map[int64]string)
+	}
+	if prefs.MutedTournaments == nil {
+		prefs.MutedTournaments = make(map[int64]string)
+	}
+	if prefs.Tracked == nil {
+		prefs.Tracked = make(map[string]trackedMatch)
+	}
+	return prefs
+}
+
+// Whether a chat muted one of the players (or pairs) or the tournament of a match
+func (p *preferenceStore) muted(chatID string, m Match) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefs, ok := p.chats[chatID]
+	if !ok {
+		return false
+	}
+	for _, id := range []int64{m.Competitor1ID, m.Competitor2ID} {
+		if _, muted := prefs.MutedPlayers[id]; muted && id != 0 {
+			return true
+		}
+	}
+	_, muted := prefs.MutedTournaments[m.TournamentID]
+	return muted && m.TournamentID != 0
+}
+
+// Mute a player in a chat, or unmute when already muted. Returns the reply.
+func (p *preferenceStore) togglePlayer(chatID string, id int64, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefs := p.chat(chatID)
+	reply := fmt.Sprintf("🔇 %s masqué", name)
+	if _, muted := prefs.MutedPlayers[id]; muted {
+		delete(prefs.MutedPlayers, id)
+		reply = fmt.Sprintf("🔊 %s réactivé", name)
+	} else {
+		prefs.MutedPlayers[id] = name
+	}
+	return reply, p.save()
+}
+
+// Mute a tournament in a chat, or unmute when already muted. Returns the reply.
+func (p *preferenceStore) toggleTournament(chatID string, id int64, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefs := p.chat(chatID)
+	reply := fmt.Sprintf("🔇 %s masqué", name)
+	if _, muted := prefs.MutedTournaments[id]; muted {
+		delete(prefs.MutedTournaments, id)
+		reply = fmt.Sprintf("🔊 %s réactivé", name)
+	} else {
+		prefs.MutedTournaments[id] = name
+	}
+	return reply, p.save()
+}
+
+// Follow a match's cotes in a chat, or stop following it. Returns the reply.
+func (p *preferenceStore) toggleTracking(chatID string, m Match, by string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefs := p.chat(chatID)
+	if _, tracked := prefs.Tracked[m.MatchID]; tracked {
+		delete(prefs.Tracked, m.MatchID)
+		for key := range p.lastCotes {
+			if strings.HasPrefix(key, chatID+"|"+m.MatchID+"|") {
+				delete(p.lastCotes, key)
+			}
+		}
+		return fmt.Sprintf("Suivi arrêté : %s", m.Joueurs), p.save()
+	}
+	prefs.Tracked[m.MatchID] = trackedMatch{
+		Joueurs:    m.Joueurs,
+		Lien:       m.Lien,
+		MatchStart: m.MatchStart,
+		By:         by,
+		Since:      time.Now(),
+	}
+	return fmt.Sprintf("📈 Cotes suivies jusqu'au début : %s", m.Joueurs), p.save()
+}
+
+// Forget the cotes recorded for a match that is over, and stop following it
+func (p *preferenceStore) forget(matchID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.lastCotes {
+		if strings.Contains(key, "|"+matchID+"|") {
+			delete(p.lastCotes, key)
+		}
+	}
+	changed := false
+	for _, prefs := range p.chats {
+		if _, ok := prefs.Tracked[matchID]; ok {
+			delete(prefs.Tracked, matchID)
+			changed = true
+		}
+	}
+	if changed {
+		if err := p.save(); err != nil {
+			slog.Error("Error saving preferences", "file", p.path, "err", err)
+		}
+	}
+}
+
+// Post the bets whose cotes moved since the last cycle to every chat following
+// the match; the first cycle after "Suivre" only records the cotes. Matches are
+// dropped once started.
+func (p *preferenceStore) observe(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	type movement struct {
+		chatID string
+		match  Match
+		bets   []Bet
+	}
+	var movements []movement
+
+	p.mu.Lock()
+	now := time.Now()
+	changed := false
+	for chatID, prefs := range p.chats {
+		for matchID, tracked := range prefs.Tracked {
+			if tracked.MatchStart > 0 && now.Unix() >= tracked.MatchStart {
+				delete(prefs.Tracked, matchID)
+				changed = true
+			}
+		}
+		for _, m := range results {
+			if _, ok := prefs.Tracked[m.Match.MatchID]; !ok {
+				continue
+			}
+			key := chatID + "|" + m.Match.MatchID + "|" + family.Name
+			previous, seen := p.lastCotes[key]
+			current := make(map[string]string)
+			var moved []Bet
+			for _, bet := range m.Bet {
+				signature := generateBetSignature(bet)
+				current[generateBetKey(bet)] = signature
+				if old, ok := previous[generateBetKey(bet)]; seen && ok && old != signature {
+					moved = append(moved, bet)
+				}
+			}
+			p.lastCotes[key] = current
+			if len(moved) > 0 {
+				movements = append(movements, movement{chatID: chatID, match: m.Match, bets: moved})
+			}
+		}
+	}
+	if changed {
+		if err := p.save(); err != nil {
+			lg.Error("Error saving preferences", "file", p.path, "err", err)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, mv := range movements {
+		var message strings.Builder
+		message.WriteString(fmt.Sprintf("📈 <b>%s</b>\nMouvement de cotes :\n\n", html.EscapeString(mv.match.displayTitle())))
+		for _, bet := range mv.bets {
+			message.WriteString(formatBet(bet) + "\n")
+		}
+		message.WriteString(fmt.Sprintf("🔗 <a href=\"%s\">LIEN</a>", mv.match.Lien))
+		if outcome, err := deliverAlert(lg, mv.chatID, "mouvement de cotes", mv.match, message.String(), PARSE_MODE_HTML, alertKeyboard(mv.match)); err != nil {
+			lg.Error("Failed to send cote movement", "chat", mv.chatID, "match", mv.match.Joueurs, "err", err)
+		} else if outcome == DELIVERY_SENT {
+			lg.Info("Cote movement sent", "chat", mv.chatID, "match", mv.match.Joueurs, "bets", len(mv.bets))
+		}
+	}
+}
+
+// Mutes and followed matches of a chat, with buttons to undo them
+func (p *preferenceStore) report(chatID string) (string, [][]inlineButton) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefs, ok := p.chats[chatID]
+	if !ok || len(prefs.MutedPlayers)+len(prefs.MutedTournaments)+len(prefs.Tracked) == 0 {
+		return "Aucune préférence enregistrée pour ce chat", nil
+	}
+
+	var message strings.Builder
+	var keyboard [][]inlineButton
+	message.WriteString("⚙️ <b>Préférences</b>\n")
+	for _, id := range sortedIDs(prefs.MutedPlayers) {
+		message.WriteString(fmt.Sprintf("🔇 %s\n", html.EscapeString(prefs.MutedPlayers[id])))
+		keyboard = append(keyboard, []inlineButton{{Text: "🔊 " + prefs.MutedPlayers[id], CallbackData: fmt.Sprintf("mute_p|%d", id)}})
+	}
+	for _, id := range sortedIDs(prefs.MutedTournaments) {
+		message.WriteString(fmt.Sprintf("🔇 🏆 %s\n", html.EscapeString(prefs.MutedTournaments[id])))
+		keyboard = append(keyboard, []inlineButton{{Text: "🔊 " + prefs.MutedTournaments[id], CallbackData: fmt.Sprintf("mute_t|%d", id)}})
+	}
+	matchIDs := make([]string, 0, len(prefs.Tracked))
+	for matchID := range prefs.Tracked {
+		matchIDs = append(matchIDs, matchID)
+	}
+	sort.Strings(matchIDs)
+	for _, matchID := range matchIDs {
+		tracked := prefs.Tracked[matchID]
+		message.WriteString(fmt.Sprintf("📈 <a href=\"%s\">%s</a>\n", tracked.Lien, html.EscapeString(tracked.Joueurs)))
+		keyboard = append(keyboard, []inlineButton{{Text: "⏹️ " + tracked.Joueurs, CallbackData: "track|" + matchID}})
+	}
+	return message.String(), keyboard
+}
+
+func sortedIDs(names map[int64]string) []int64 {
+	ids := make([]int64, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return names[ids[i]] < names[ids[j]] })
+	return ids
+}
+
+// Buttons under every alert: open the match, follow its cotes, record a bet,
+// then mute either player (or pair) and the tournament. Pressing a mute or
+// follow button again undoes it; /prefs lists them.
+func alertKeyboard(m Match) [][]inlineButton {
+	if m.MatchID == "" {
+		return nil
+	}
+	// Without the bot nothing would answer the other buttons
+	if !config.Bot.Enabled {
+		return [][]inlineButton{{{Text: "🔗 Ouvrir", URL: m.Lien}}}
+	}
+	keyboard := [][]inlineButton{{
+		{Text: "🔗 Ouvrir", URL: m.Lien},
+		{Text: "📈 Suivre", CallbackData: "track|" + m.MatchID},
+		{Text: "🎯 J'ai parié", CallbackData: "pick|" + m.MatchID},
+	}}
+
+	var mutes []inlineButton
+	sides := titleSides(m.Joueurs)
+	for i, id := range []int64{m.Competitor1ID, m.Competitor2ID} {
+		if id == 0 || i >= len(sides) {
+			continue
+		}
+		mutes = append(mutes, inlineButton{
+			Text:         "🔇 " + strings.Join(sides[i], " / "),
+			CallbackData: fmt.Sprintf("mute_p|%d", id),
+		})
+	}
+	if len(mutes) > 0 {
+		keyboard = append(keyboard, mutes)
+	}
+	if m.TournamentID != 0 {
+		name := m.Tournoi
+		if name == "" {
+			name = "ce tournoi"
+		}
+		keyboard = append(keyboard, []inlineButton{{
+			Text:         "🔇 " + name,
+			CallbackData: fmt.Sprintf("mute_t|%d|%s", m.TournamentID, m.MatchID),
+		}})
+	}
+	return keyboard
+}
+
+// Handle the preference buttons; ok is false for other callback data
+func handlePreferenceCallback(chatID string, parts []string, by string) (reply string, ok bool, err error) {
+	if len(parts) < 2 {
+		return "", false, nil
+	}
+	switch parts[0] {
+	case "mute_p":
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		name := players.name(id)
+		if name == "" {
+			name = "Joueur " + parts[1]
+		}
+		reply, err = preferences.togglePlayer(chatID, id, name)
+	case "mute_t":
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		name := preferences.tournamentName(chatID, id)
+		if len(parts) > 2 {
+			if m, found := recentMatches.get(parts[2]); found && m.Match.Tournoi != "" {
+				name = m.Match.Tournoi
+			}
+		}
+		if name == "" {
+			name = "Tournoi " + parts[1]
+		}
+		reply, err = preferences.toggleTournament(chatID, id, name)
+	case "track":
+		m, found := recentMatches.get(parts[1])
+		if !found {
+			if tracked, ok := preferences.tracked(chatID, parts[1]); ok {
+				m.Match = Match{MatchID: parts[1], Joueurs: tracked.Joueurs, Lien: tracked.Lien}
+			} else {
+				return fmt.Sprintf("❌ Match %s inconnu", parts[1]), true, nil
+			}
+		}
+		reply, err = preferences.toggleTracking(chatID, m.Match, by)
+	default:
+		return "", false, nil
+	}
+	return reply, true, err
+}
+
+// Name a muted tournament was saved under, for the unmute button of /prefs
+func (p *preferenceStore) tournamentName(chatID string, id int64) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prefs, ok := p.chats[chatID]; ok {
+		return prefs.MutedTournaments[id]
+	}
+	return ""
+}
+
+func (p *preferenceStore) tracked(chatID, matchID string) (trackedMatch, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prefs, ok := p.chats[chatID]; ok {
+		tracked, ok := prefs.Tracked[matchID]
+		return tracked, ok
+	}
+	return trackedMatch{}, false
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Pricing checks run on every cycle's results
+type PricingConfig struct {
+	MarginAlertBelow float64  `json:"margin_alert_below"` // alert when a market's overround is below this (0.03 = 3%, 0 = off)
+	Dispersion       float64  `json:"dispersion"`         // variance/mean of the count model: 1 = Poisson, above = negative binomial
+	ValueMinEdge     float64  `json:"value_min_edge"`     // alert on ladder options whose expected value is at least this (0 = off)
+	Consistency      []string `json:"consistency"`        // families whose ladders and lines are checked against each other
+	TotalTolerance   float64  `json:"total_tolerance"`    // max gap between the posted and the players' implied total over probability (0 = off)
+	Channel          string   `json:"channel"`            // where pricing alerts go (default: the family's channel)
+}
+
+// Overround of a complete set of exclusive prices: sum of 1/cote minus 1
+func overround(cotes ...float64) float64 {
+	sum := 0.0
+	for _, cote := range cotes {
+		if cote <= 0 {
+			return 0
+		}
+		sum += 1 / cote
+	}
+	return sum - 1
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// Templates whose outcomes cover every result, so their overround means something
+func isExhaustiveTemplate(template string) bool {
+	switch template {
+	case TEMPLATE_2WAY, TEMPLATE_3WAY, TEMPLATE_ASIAN_HANDICAP:
+		return true
+	}
+	return false
+}
+
+// Compute margins and implied probabilities for every bet of a match.
+// Over/Under pairs get their overround and margin-free probabilities, complete
+// 2way/3way/handicap markets their overround. Ladder options are "N or more" and
+// not exclusive, so each option is compared with the margin-free probability of
+// the Over/Under line at N-0.5 for the same player, when Winamax posts one.
+func annotateMargins(m *MatchData) {
+	for i := range m.Bet {
+		bet := &m.Bet[i]
+		switch {
+		case bet.isOverUnder() && bet.Plus > 0 && bet.Moins > 0:
+			over := overround(bet.Plus, bet.Moins)
+			bet.Marge = round4(over)
+			bet.ProbaPlus = round4((1 / bet.Plus) / (1 + over))
+			bet.ProbaMoins = round4((1 / bet.Moins) / (1 + over))
+		case bet.isOutcomeList() && isExhaustiveTemplate(bet.Template):
+			cotes := make([]float64, len(bet.Outcomes))
+			for j, oc := range bet.Outcomes {
+				cotes[j] = oc.Cote
+			}
+			bet.Marge = round4(overround(cotes...))
+		}
+	}
+
+	for i := range m.Bet {
+		bet := &m.Bet[i]
+		if !bet.isLadder() {
+			continue
+		}
+		side := betSide(*bet, m.Match)
+		total, n := 0.0, 0
+		for j := range bet.Options {
+			opt := &bet.Options[j]
+			line, ok := overUnderAt(m, side, opt.Seuil-0.5)
+			if !ok || opt.Cote <= 0 || line.ProbaPlus <= 0 {
+				continue
+			}
+			opt.Marge = round4((1/opt.Cote)/line.ProbaPlus - 1)
+			total += opt.Marge
+			n++
+		}
+		// The ladder's margin is the average over the options that could be estimated
+		if n > 0 {
+			bet.Marge = round4(total / float64(n))
+		}
+	}
+}
+
+// Find the annotated Over/Under line of a player (side 1 or 2) or of the match (0) at a cut
+func overUnderAt(m *MatchData, side int, cut float64) (Bet, bool) {
+	for _, bet := range m.Bet {
+		if bet.isOverUnder() && bet.Cut == cut && betSide(bet, m.Match) == side {
+			return bet, true
+		}
+	}
+	return Bet{}, false
+}
+
+// " (marge 4.8%)" when the margin is known
+func formatMarge(marge float64) string {
+	if marge == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (marge %.1f%%)", marge*100)
+}
+
+// Lines describing one bet in a notification: Over/Under on one line, ladders and
+// other templates as a title followed by one line per option/outcome
+func formatBet(bet Bet) string {
+	return formatBetAs(bet, htmlMarkup)
+}
+
+// Same lines in the markup of a parse mode, bet names and labels escaped
+func formatBetAs(bet Bet, mk markup) string {
+	var b strings.Builder
+	switch {
+	case bet.isOverUnder():
+		b.WriteString(mk.bold(bet.Type) + mk.escape(fmt.Sprintf("  + %.1f @ %.2f / - %.1f @ %.2f%s",
+			bet.Cut, roundCote(bet.Plus), bet.Cut, roundCote(bet.Moins), formatMarge(bet.Marge))) + "\n")
+		if bet.ProbaPlus > 0 {
+			b.WriteString(mk.escape(fmt.Sprintf("Proba : + %.0f%% / - %.0f%%", bet.ProbaPlus*100, bet.ProbaMoins*100)) + "\n")
+		}
+	case bet.isLadder():
+		b.WriteString(mk.bold(bet.Type) + mk.escape(" :"+formatMarge(bet.Marge)) + "\n")
+		for _, opt := range bet.Options {
+			b.WriteString(mk.escape(fmt.Sprintf("%.0f @ %.2f%s%s", opt.Seuil, roundCote(opt.Cote), formatMarge(opt.Marge), formatFair(opt))) + "\n")
+		}
+	default:
+		b.WriteString(mk.bold(bet.Type) + mk.escape(" :"+formatMarge(bet.Marge)) + "\n")
+		for _, oc := range bet.Outcomes {
+			b.WriteString(mk.escape(fmt.Sprintf("%s @ %.2f", oc.Label, roundCote(oc.Cote))) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Alerts already sent, keyed by match|bet|kind, with the signature of the prices
+// they were sent for so an alert is repeated only when the prices move
+type alertTracker struct {
+	mu   sync.Mutex
+	sent map[string]string
+}
+
+var pricingAlerts = &alertTracker{sent: make(map[string]string)}
+
+// Forget the alerts sent on a match (keys start with its link)
+func (t *alertTracker) forget(link string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.sent {
+		if strings.HasPrefix(key, link+"|") {
+			delete(t.sent, key)
+		}
+	}
+}
+
+func (t *alertTracker) shouldSend(key, signature string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sent[key] == signature {
+		return false
+	}
+	t.sent[key] = signature
+	return true
+}
+
+func pricingChannel(family MarketFamily) string {
+	if config.Pricing.Channel != "" {
+		return config.Pricing.Channel
+	}
+	return family.Channel
+}
+
+// Alert on markets whose overround is below the configured threshold
+func checkMargins(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	threshold := config.Pricing.MarginAlertBelow
+	if threshold <= 0 {
+		return
+	}
+	for _, m := range results {
+		if preferences.muted(pricingChannel(family), m.Match) {
+			continue
+		}
+		for _, bet := range m.Bet {
+			if bet.Marge == 0 || bet.Marge >= threshold {
+				continue
+			}
+			key := m.Match.Lien + "|" + generateLineKey(bet) + "|marge"
+			if !pricingAlerts.shouldSend(key, generateBetSignature(bet)) {
+				continue
+			}
+
+			var message strings.Builder
+			message.WriteString(fmt.Sprintf("📉 <b>%s</b>\n", html.EscapeString(m.Match.displayTitle())))
+			message.WriteString(fmt.Sprintf("Marge faible : %.1f%% (seuil %.1f%%)\n\n", bet.Marge*100, threshold*100))
+			message.WriteString(formatBet(bet))
+			message.WriteString(fmt.Sprintf("\n🔗 <a href=\"%s\">LIEN</a>", m.Match.Lien))
+
+			if outcome, err := deliverAlert(lg, pricingChannel(family), "marge faible", m.Match, message.String(), PARSE_MODE_HTML, alertKeyboard(m.Match)); err != nil {
+				lg.Error("Failed to send margin alert", "match", m.Match.Joueurs, "err", err)
+			} else if outcome == DELIVERY_SENT {
+				lg.Info("Margin alert sent", "match", m.Match.Joueurs, "bet", bet.Type, "marge", bet.Marge)
+			}
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Alert rule expressions, e.g.
+//
+//	market == "ladder" && player in watchlist && seuil >= 10 && cote >= 2.2 && hours_to_start < 24
+//
+// Operators: || && ! == != < <= > >= in, parentheses, numbers, "strings",
+// [lists] and the names of ruleVars and of the configured lists. Expressions are
+// type-checked when compiled, so a typo is reported at load time, not per bet.
+
+type ruleKind int
+
+const (
+	KIND_NUMBER ruleKind = iota
+	KIND_STRING
+	KIND_BOOL
+	KIND_LIST
+)
+
+func (k ruleKind) String() string {
+	return [...]string{"number", "string", "bool", "list"}[k]
+}
+
+type ruleValue struct {
+	num  float64
+	str  string
+	b    bool
+	list []string
+}
+
+// A compiled expression node with its static type
+type ruleNode interface {
+	kind() ruleKind
+	eval(item *ruleItem) ruleValue
+}
+
+type ruleConst struct {
+	k ruleKind
+	v ruleValue
+}
+
+func (n ruleConst) kind() ruleKind           { return n.k }
+func (n ruleConst) eval(*ruleItem) ruleValue { return n.v }
+
+// A variable read from the bet option being evaluated (see ruleVars)
+type ruleVar struct {
+	k   ruleKind
+	get func(*ruleItem) ruleValue
+}
+
+func (n ruleVar) kind() ruleKind                { return n.k }
+func (n ruleVar) eval(item *ruleItem) ruleValue { return n.get(item) }
+
+type ruleUnary struct{ operand ruleNode }
+
+func (n ruleUnary) kind() ruleKind { return KIND_BOOL }
+func (n ruleUnary) eval(item *ruleItem) ruleValue {
+	return ruleValue{b: !n.operand.eval(item).b}
+}
+
+type ruleBinary struct {
+	op          string
+	left, right ruleNode
+}
+
+func (n ruleBinary) kind() ruleKind { return KIND_BOOL }
+
+func (n ruleBinary) eval(item *ruleItem) ruleValue {
+	switch n.op {
+	case "&&":
+		return ruleValue{b: n.left.eval(item).b && n.right.eval(item).b}
+	case "||":
+		return ruleValue{b: n.left.eval(item).b || n.right.eval(item).b}
+	case "in":
+		return ruleValue{b: inList(n.left.eval(item).str, n.right.eval(item).list)}
+	}
+
+	l, r := n.left.eval(item), n.right.eval(item)
+	switch n.left.kind() {
+	case KIND_NUMBER:
+		switch n.op {
+		case "==":
+			return ruleValue{b: math.Abs(l.num-r.num) < 1e-9}
+		case "!=":
+			return ruleValue{b: math.Abs(l.num-r.num) >= 1e-9}
+		case "<":
+			return ruleValue{b: l.num < r.num}
+		case "<=":
+			return ruleValue{b: l.num <= r.num+1e-9}
+		case ">":
+			return ruleValue{b: l.num > r.num}
+		case ">=":
+			return ruleValue{b: l.num >= r.num-1e-9}
+		}
+	case KIND_STRING:
+		equal := foldName(l.str) == foldName(r.str)
+		return ruleValue{b: equal == (n.op == "==")}
+	case KIND_BOOL:
+		return ruleValue{b: (l.b == r.b) == (n.op == "==")}
+	}
+	return ruleValue{}
+}
+
+// A name is in a list when one of the entries appears in it as whole words,
+// whatever the case or accents, so "alcaraz" matches "C. Alcaraz"
+func inList(value string, list []string) bool {
+	text := " " + normalizePlayerText(value) + " "
+	for _, entry := range list {
+		if entry = normalizePlayerText(entry); entry != "" && strings.Contains(text, " "+entry+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+type ruleToken struct {
+	text string
+	kind string // num, str, ident, op
+	pos  int
+}
+
+func tokenizeRule(src string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, ruleToken{text: string(runes[start:i]), kind: "num", pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(runes) && runes[i] != c {
+				b.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, ruleToken{text: b.String(), kind: "str", pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, ruleToken{text: string(runes[start:i]), kind: "ident", pos: start})
+		default:
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch {
+			case two == "&&" || two == "||" || two == "==" || two == "!=" || two == "<=" || two == ">=":
+				tokens = append(tokens, ruleToken{text: two, kind: "op", pos: i})
+				i += 2
+			case strings.ContainsRune("!<>()[],", c):
+				tokens = append(tokens, ruleToken{text: string(c), kind: "op", pos: i})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// Recursive descent parser: or := and ("||" and)*, and := not ("&&" not)*,
+// not := "!" not | cmp, cmp := primary [op primary]
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+	lists  map[string][]string
+}
+
+// Compile an expression against the variables and the configured lists
+func compileRule(src string, lists map[string][]string) (ruleNode, error) {
+	tokens, err := tokenizeRule(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens, lists: lists}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	if node.kind() != KIND_BOOL {
+		return nil, fmt.Errorf("a rule must be a condition, not a %s", node.kind())
+	}
+	return node, nil
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "op" {
+		return p.tokens[p.pos].text
+	}
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "ident" && p.tokens[p.pos].text == "in" {
+		return "in"
+	}
+	return ""
+}
+
+func (p *ruleParser) expect(op string) error {
+	if p.peek() != op {
+		return fmt.Errorf("expected %q %s", op, p.where())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *ruleParser) where() string {
+	if p.pos >= len(p.tokens) {
+		return "at the end of the rule"
+	}
+	return fmt.Sprintf("at position %d", p.tokens[p.pos].pos)
+}
+
+func (p *ruleParser) parseOr() (ruleNode, error) {
+	return p.parseChain("||", p.parseAnd)
+}
+
+func (p *ruleParser) parseAnd() (ruleNode, error) {
+	return p.parseChain("&&", p.parseNot)
+}
+
+func (p *ruleParser) parseChain(op string, next func() (ruleNode, error)) (ruleNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == op {
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != KIND_BOOL || right.kind() != KIND_BOOL {
+			return nil, fmt.Errorf("%s needs conditions on both sides", op)
+		}
+		left = ruleBinary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseNot() (ruleNode, error) {
+	if p.peek() != "!" {
+		return p.parseComparison()
+	}
+	p.pos++
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if operand.kind() != KIND_BOOL {
+		return nil, fmt.Errorf("! needs a condition")
+	}
+	return ruleUnary{operand: operand}, nil
+}
+
+func (p *ruleParser) parseComparison() (ruleNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "in":
+	default:
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case op == "in":
+		if left.kind() != KIND_STRING || right.kind() != KIND_LIST {
+			return nil, fmt.Errorf("in needs a string on the left and a list on the right")
+		}
+	case left.kind() != right.kind():
+		return nil, fmt.Errorf("%s compares a %s with a %s", op, left.kind(), right.kind())
+	case op != "==" && op != "!=" && left.kind() != KIND_NUMBER:
+		return nil, fmt.Errorf("%s only compares numbers", op)
+	case left.kind() == KIND_LIST:
+		return nil, fmt.Errorf("%s doesn't compare lists", op)
+	}
+	return ruleBinary{op: op, left: left, right: right}, nil
+}
+
+func (p *ruleParser) parsePrimary() (ruleNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("value expected at the end of the rule")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case "num":
+		num, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return ruleConst{k: KIND_NUMBER, v: ruleValue{num: num}}, nil
+	case "str":
+		return ruleConst{k: KIND_STRING, v: ruleValue{str: tok.text}}, nil
+	case "ident":
+		switch tok.text {
+		case "true", "false":
+			return ruleConst{k: KIND_BOOL, v: ruleValue{b: tok.text == "true"}}, nil
+		}
+		if v, ok := ruleVars[tok.text]; ok {
+			return v, nil
+		}
+		if list, ok := p.lists[tok.text]; ok {
+			return ruleConst{k: KIND_LIST, v: ruleValue{list: list}}, nil
+		}
+		return nil, fmt.Errorf("unknown name %q at position %d", tok.text, tok.pos)
+	}
+
+	switch tok.text {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case "[":
+		var list []string
+		for p.peek() != "]" {
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "str" {
+				return nil, fmt.Errorf("string expected in list %s", p.where())
+			}
+			list = append(list, p.tokens[p.pos].text)
+			p.pos++
+			if p.peek() == "," {
+				p.pos++
+			}
+		}
+		p.pos++
+		return ruleConst{k: KIND_LIST, v: ruleValue{list: list}}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
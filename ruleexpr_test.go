@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileRuleErrors(t *testing.T) {
+	lists := map[string][]string{"watchlist": {"alcaraz"}}
+	tests := []struct {
+		src  string
+		want string // part of the error
+	}{
+		{`seuil`, "a rule must be a condition, not a number"},
+		{`seuil >=`, "value expected at the end of the rule"},
+		{`seuil > 1 seuil`, `unexpected "seuil" at position 10`},
+		{`(seuil > 1`, `expected ")" at the end of the rule`},
+		{`seuil > 1 & cote > 2`, "unexpected character '&' at position 10"},
+		{`market == "ladder`, "unterminated string at position 10"},
+		{`seuil > 1.2.3`, `invalid number "1.2.3"`},
+		{`seuils > 1`, `unknown name "seuils" at position 0`},
+		{`cote == "2"`, "== compares a number with a string"},
+		{`market < "ladder"`, "< only compares numbers"},
+		{`watchlist == watchlist`, "== doesn't compare lists"},
+		{`player in "alcaraz"`, "in needs a string on the left and a list on the right"},
+		{`player in [1]`, "string expected in list at position 11"},
+		{`seuil && is_new`, "&& needs conditions on both sides"},
+		{`is_new || cote`, "|| needs conditions on both sides"},
+		{`!cote`, "! needs a condition"},
+	}
+	for _, tt := range tests {
+		_, err := compileRule(tt.src, lists)
+		if err == nil {
+			t.Errorf("compileRule(%q) = nil error, want %q", tt.src, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compileRule(%q) = %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestRulePrecedence(t *testing.T) {
+	lists := map[string][]string{"watchlist": {"alcaraz", "sinner"}}
+	item := &ruleItem{
+		market: "ladder",
+		player: "C. Alcaraz",
+		seuil:  10,
+		cote:   2.204,
+		isNew:  true,
+	}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		// && binds tighter than ||
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && true || true`, true},
+		{`false && (true || true)`, false},
+		// ! applies to the whole comparison and binds tighter than && and ||
+		{`!seuil > 12`, true},
+		{`!false && false`, false},
+		{`!(false || true)`, false},
+		{`!!is_new`, true},
+		// comparisons on numbers, the displayed cote and case-insensitive strings
+		{`seuil >= 10 && seuil <= 10 && seuil == 10`, true},
+		{`cote == 2.2 && cote > 2.19 && cote < 2.21`, true},
+		{`market == "LADDER" && market != "over"`, true},
+		{`player in watchlist && !(player in ["sinner"])`, true},
+		{`market == "over" || seuil > 5 && player in watchlist`, true},
+		{`(market == "over" || seuil > 5) && !is_new`, false},
+	}
+	for _, tt := range tests {
+		node, err := compileRule(tt.src, lists)
+		if err != nil {
+			t.Errorf("compileRule(%q): %v", tt.src, err)
+			continue
+		}
+		if got := node.eval(item).b; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert rules: conditions on each bet option, attached to a chat (a channel,
+// a group, or a user's private chat). Filter rules narrow the new bet
+// notifications of the channel they name; alert rules post every option they
+// match, new or not, again only when its price changes.
+type RulesConfig struct {
+	ReloadSeconds int                 `json:"reload_seconds"` // how often the config file is checked for rule changes (0 = never)
+	Lists         map[string][]string `json:"lists"`          // named lists usable in rules, e.g. watchlist
+	Rules         []AlertRule         `json:"rules"`
+}
+
+type AlertRule struct {
+	Name     string   `json:"name"`
+	Chat     string   `json:"chat"`     // channel or user chat ID
+	Mode     string   `json:"mode"`     // filter (default) or alert
+	Families []string `json:"families"` // market families the rule applies to (empty = all)
+	When     string   `json:"when"`     // expression, see ruleexpr.go
+}
+
+const (
+	RULE_FILTER = "filter"
+	RULE_ALERT  = "alert"
+)
+
+// One priced option of a bet as rules see it
+type ruleItem struct {
+	family   MarketFamily
+	match    Match
+	bet      Bet
+	market   string // ladder, over, under or outcome
+	label    string // outcome label
+	player   string // player (or doubles pair) the bet is on, "" for the match
+	seuil    float64
+	cote     float64
+	marge    float64
+	proba    float64 // margin-free or fair probability when known
+	fairCote float64
+	value    float64
+	isNew    bool
+	now      time.Time
+}
+
+func ruleNumber(get func(*ruleItem) float64) ruleVar {
+	return ruleVar{k: KIND_NUMBER, get: func(i *ruleItem) ruleValue { return ruleValue{num: get(i)} }}
+}
+
+func ruleString(get func(*ruleItem) string) ruleVar {
+	return ruleVar{k: KIND_STRING, get: func(i *ruleItem) ruleValue { return ruleValue{str: get(i)} }}
+}
+
+func ruleBool(get func(*ruleItem) bool) ruleVar {
+	return ruleVar{k: KIND_BOOL, get: func(i *ruleItem) ruleValue { return ruleValue{b: get(i)} }}
+}
+
+// Variables a rule can use. Numbers that aren't known (no fair price, no start
+// time) are NaN, so any comparison with them is false. cote is the displayed
+// one, as users read it in alerts.
+var ruleVars = map[string]ruleVar{
+	"market":     ruleString(func(i *ruleItem) string { return i.market }),
+	"type":       ruleString(func(i *ruleItem) string { return i.bet.Type }),
+	"label":      ruleString(func(i *ruleItem) string { return i.label }),
+	"player":     ruleString(func(i *ruleItem) string { return i.player }),
+	"joueurs":    ruleString(func(i *ruleItem) string { return i.match.Joueurs }),
+	"family":     ruleString(func(i *ruleItem) string { return i.family.Name }),
+	"sport":      ruleString(func(i *ruleItem) string { return i.family.sport }),
+	"book":       ruleString(func(i *ruleItem) string { return i.family.bookName() }),
+	"tournament": ruleString(func(i *ruleItem) string { return i.match.Tournoi }),
+	"category":   ruleString(func(i *ruleItem) string { return i.match.Categorie }),
+	"round":      ruleString(func(i *ruleItem) string { return i.match.Tour }),
+	"seuil":      ruleNumber(func(i *ruleItem) float64 { return i.seuil }),
+	"cote":       ruleNumber(func(i *ruleItem) float64 { return roundCote(i.cote) }),
+	"marge":      ruleNumber(func(i *ruleItem) float64 { return unknownIfZero(i.marge) }),
+	"proba":      ruleNumber(func(i *ruleItem) float64 { return unknownIfZero(i.proba) }),
+	"cote_juste": ruleNumber(func(i *ruleItem) float64 { return unknownIfZero(i.fairCote) }),
+	"value":      ruleNumber(func(i *ruleItem) float64 { return unknownIfZero(i.value) }),
+	"hours_to_start": ruleNumber(func(i *ruleItem) float64 {
+		if i.match.MatchStart == 0 {
+			return math.NaN()
+		}
+		return float64(i.match.MatchStart-i.now.Unix()) / 3600
+	}),
+	"is_player": ruleBool(func(i *ruleItem) bool { return i.player != "" }),
+	"doubles":   ruleBool(func(i *ruleItem) bool { return i.match.isDoubles() }),
+	"is_new":    ruleBool(func(i *ruleItem) bool { return i.isNew }),
+}
+
+func unknownIfZero(v float64) float64 {
+	if v == 0 {
+		return math.NaN()
+	}
+	return v
+}
+
+type compiledRule struct {
+	AlertRule
+	expr ruleNode
+}
+
+func (r *compiledRule) appliesTo(family MarketFamily) bool {
+	return len(r.Families) == 0 || slices.Contains(r.Families, family.Name)
+}
+
+// Rules in use, swapped as a whole when the config file changes
+type ruleSet struct {
+	mu    sync.RWMutex
+	rules []*compiledRule
+}
+
+var alertRules = &ruleSet{}
+
+// Compile every rule; any error rejects the whole set so a typo never
+// silently drops a filter
+func compileRules(cfg RulesConfig) ([]*compiledRule, error) {
+	var rules []*compiledRule
+	for i, rule := range cfg.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			rule.Name = name
+		}
+		if rule.Chat == "" {
+			return nil, fmt.Errorf("rule %s has no chat", name)
+		}
+		switch rule.Mode {
+		case "":
+			rule.Mode = RULE_FILTER
+		case RULE_FILTER, RULE_ALERT:
+		default:
+			return nil, fmt.Errorf("rule %s has an unknown mode %q", name, rule.Mode)
+		}
+		expr, err := compileRule(rule.When, cfg.Lists)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		rules = append(rules, &compiledRule{AlertRule: rule, expr: expr})
+	}
+	return rules, nil
+}
+
+func (s *ruleSet) set(rules []*compiledRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = rules
+}
+
+// Rules of one mode for a family, optionally only those of a chat
+func (s *ruleSet) find(family MarketFamily, mode, chat string) []*compiledRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found []*compiledRule
+	for _, rule := range s.rules {
+		if rule.Mode == mode && rule.appliesTo(family) && (chat == "" || rule.Chat == chat) {
+			found = append(found, rule)
+		}
+	}
+	return found
+}
+
+// Keep the options of the bets that pass at least one rule: ladder options and
+// outcomes one by one, an Over/Under line when either side passes
+func selectBets(rules []*compiledRule, family MarketFamily, match Match, bets []Bet, isNew func(Bet) bool) []Bet {
+	now := time.Now()
+	passes := func(item *ruleItem) bool {
+		for _, rule := range rules {
+			if rule.expr.eval(item).b {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected []Bet
+	for _, bet := range bets {
+		base := ruleItem{
+			family: family,
+			match:  match,
+			bet:    bet,
+			player: sidePlayer(bet.Type, match.Joueurs, betSide(bet, match)),
+			marge:  bet.Marge,
+			isNew:  isNew(bet),
+			now:    now,
+		}
+		switch {
+		case bet.isLadder():
+			var kept []Option
+			for _, opt := range bet.Options {
+				item := base
+				item.market, item.seuil, item.cote = "ladder", opt.Seuil, opt.Cote
+				item.proba, item.fairCote, item.value = opt.Proba, opt.CoteJuste, opt.Value
+				if opt.Marge != 0 {
+					item.marge = opt.Marge
+				}
+				if passes(&item) {
+					kept = append(kept, opt)
+				}
+			}
+			if len(kept) > 0 {
+				bet.Options = kept
+				selected = append(selected, bet)
+			}
+		case bet.isOverUnder():
+			over, under := base, base
+			over.market, over.seuil, over.cote, over.proba = "over", bet.Cut, bet.Plus, bet.ProbaPlus
+			under.market, under.seuil, under.cote, under.proba = "under", bet.Cut, bet.Moins, bet.ProbaMoins
+			if passes(&over) || passes(&under) {
+				selected = append(selected, bet)
+			}
+		default:
+			var kept []Outcome
+			for _, oc := range bet.Outcomes {
+				item := base
+				item.market, item.label, item.cote = "outcome", oc.Label, oc.Cote
+				if passes(&item) {
+					kept = append(kept, oc)
+				}
+			}
+			if len(kept) > 0 {
+				bet.Outcomes = kept
+				selected = append(selected, bet)
+			}
+		}
+	}
+	return selected
+}
+
+// New bets of a match narrowed by the filter rules of the notification channel;
+// unchanged when the channel has none
+func (s *ruleSet) filterNew(family MarketFamily, match Match, bets []Bet) []Bet {
+	rules := s.find(family, RULE_FILTER, family.Channel)
+	if len(rules) == 0 {
+		return bets
+	}
+	return selectBets(rules, family, match, bets, func(Bet) bool { return true })
+}
+
+// Post what each alert rule matches to its chat, again only when the prices move
+func checkRules(family MarketFamily, results []MatchData, lg *slog.Logger) {
+	rules := alertRules.find(family, RULE_ALERT, "")
+	if len(rules) == 0 {
+		return
+	}
+	previous := buildBetMap(loadPreviousData(family.pastFile()))
+
+	for _, rule := range rules {
+		for _, m := range results {
+			if preferences.muted(rule.Chat, m.Match) {
+				continue
+			}
+			known := previous[m.Match.Lien]
+			isNew := func(bet Bet) bool {
+				_, exists := known[bet.Type]
+				return !exists
+			}
+			selected := selectBets([]*compiledRule{rule}, family, m.Match, m.Bet, isNew)
+			if len(selected) == 0 {
+				continue
+			}
+
+			var signatures []string
+			for _, bet := range selected {
+				signatures = append(signatures, generateBetSignature(bet))
+			}
+			key := m.Match.Lien + "|" + rule.Name + "|" + rule.Chat + "|rule"
+			if !pricingAlerts.shouldSend(key, strings.Join(signatures, "#")) {
+				continue
+			}
+
+			var message strings.Builder
+			message.WriteString(fmt.Sprintf("🎯 <b>%s</b>\n", html.EscapeString(m.Match.displayTitle())))
+			message.WriteString(fmt.Sprintf("Règle : %s\n\n", html.EscapeString(rule.Name)))
+			for _, bet := range selected {
+				message.WriteString(formatBet(bet) + "\n")
+			}
+			message.WriteString(fmt.Sprintf("🔗 <a href=\"%s\">LIEN</a>", m.Match.Lien))
+
+			if outcome, err := deliverAlert(lg, rule.Chat, "règle "+rule.Name, m.Match, message.String(), PARSE_MODE_HTML, alertKeyboard(m.Match)); err != nil {
+				lg.Error("Failed to send rule alert", "rule", rule.Name, "match", m.Match.Joueurs, "err", err)
+			} else if outcome == DELIVERY_SENT {
+				lg.Info("Rule alert sent", "rule", rule.Name, "match", m.Match.Joueurs, "bets", len(selected))
+			}
+		}
+	}
+}
+
+// Watch the config file and swap the rules when it changes, so analysts can
+// edit them without a restart. Only alert_rules is reloaded; a file that
+// doesn't parse or a rule that doesn't compile keeps the rules in use.
+func runRulesReload(path string, every time.Duration, lg *slog.Logger) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+	for {
+		time.Sleep(every)
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			lg.Error("Error reading config file for rules", "file", path, "err", err)
+			continue
+		}
+		var file struct {
+			AlertRules RulesConfig `json:"alert_rules"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			lg.Error("Error parsing config file, rules unchanged", "file", path, "err", err)
+			continue
+		}
+		rules, err := compileRules(file.AlertRules)
+		if err != nil {
+			lg.Error("Invalid alert rule, rules unchanged", "err", err)
+			continue
+		}
+		alertRules.set(rules)
+		lg.Info(fmt.Sprintf("🎯 %d règle(s) rechargée(s)", len(rules)), "rules", len(rules))
+	}
+}
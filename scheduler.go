@@ -0,0 +1,219 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Polling interval for matches starting within a number of hours
+type PollTier struct {
+	WithinHours  float64 `json:"within_hours"`
+	EverySeconds int     `json:"every_seconds"`
+}
+
+// How often each match is refetched, and the global budget of match requests
+type ScheduleConfig struct {
+	Tiers             []PollTier `json:"tiers"`               // first tier whose window contains the start wins, the last one covers the rest
+	ActiveFactor      float64    `json:"active_factor"`       // interval multiplier while prices move (0.5 = twice as often)
+	NightStart        int        `json:"night_start"`         // local hour the quiet period starts
+	NightEnd          int        `json:"night_end"`           // local hour it ends
+	NightFactor       float64    `json:"night_factor"`        // interval multiplier during the quiet period
+	ListEverySeconds  int        `json:"list_every_seconds"`  // longest wait between two reads of the sport page (new matches, statuses)
+	RequestsPerMinute int        `json:"requests_per_minute"` // sessions, sport pages and match requests of all watchers together (0 = unlimited)
+}
+
+// Shortest wait between two cycles of a watcher, whatever is due
+const MIN_CYCLE_PAUSE = 2 * time.Second
+
+func defaultSchedule() ScheduleConfig {
+	return ScheduleConfig{
+		Tiers: []PollTier{
+			{WithinHours: 1, EverySeconds: 15},
+			{WithinHours: 6, EverySeconds: 60},
+			{WithinHours: 24, EverySeconds: 300},
+			{WithinHours: 0, EverySeconds: 1800},
+		},
+		ActiveFactor:      0.5,
+		NightStart:        1,
+		NightEnd:          7,
+		NightFactor:       2,
+		ListEverySeconds:  15,
+		RequestsPerMinute: 120,
+	}
+}
+
+// Interval for a match from its time to start, whether its prices just moved and the hour
+func (c ScheduleConfig) interval(matchStart int64, active bool, now time.Time) time.Duration {
+	every := 15 * time.Second
+	if len(c.Tiers) > 0 {
+		every = time.Duration(c.Tiers[len(c.Tiers)-1].EverySeconds) * time.Second
+		if matchStart > 0 {
+			hours := time.Unix(matchStart, 0).Sub(now).Hours()
+			for _, tier := range c.Tiers {
+				if tier.WithinHours > 0 && hours <= tier.WithinHours {
+					every = time.Duration(tier.EverySeconds) * time.Second
+					break
+				}
+			}
+		}
+	}
+
+	factor := 1.0
+	if active && c.ActiveFactor > 0 {
+		factor *= c.ActiveFactor
+	}
+	if c.isNight(now) && c.NightFactor > 0 {
+		factor *= c.NightFactor
+	}
+	return time.Duration(float64(every) * factor)
+}
+
+// Wait before the next cycle: until the next match is due, but no longer than
+// list_every_seconds so new matches and status changes are still picked up
+func (c ScheduleConfig) pause(nextDue, now time.Time) time.Duration {
+	wait := time.Duration(c.ListEverySeconds) * time.Second
+	if wait <= 0 {
+		wait = 15 * time.Second
+	}
+	if !nextDue.IsZero() {
+		wait = min(wait, nextDue.Sub(now))
+	}
+	return max(wait, MIN_CYCLE_PAUSE)
+}
+
+func (c ScheduleConfig) isNight(now time.Time) bool {
+	if c.NightStart == c.NightEnd {
+		return false
+	}
+	hour := now.Hour()
+	if c.NightStart < c.NightEnd {
+		return hour >= c.NightStart && hour < c.NightEnd
+	}
+	return hour >= c.NightStart || hour < c.NightEnd
+}
+
+type matchSchedule struct {
+	next      time.Time
+	signature string
+}
+
+// Per-watcher schedule of match fetches, with the bets of the last fetch so
+// matches that aren't due still show up in every cycle's results
+type pollScheduler struct {
+	matches map[string]*matchSchedule
+	bets    map[string]map[string][]Bet // matchId -> family -> bets
+}
+
+func newPollScheduler() *pollScheduler {
+	return &pollScheduler{
+		matches: make(map[string]*matchSchedule),
+		bets:    make(map[string]map[string][]Bet),
+	}
+}
+
+// Whether a match has to be fetched now - always true before its first fetch
+func (s *pollScheduler) due(matchID string, now time.Time) bool {
+	m, ok := s.matches[matchID]
+	return !ok || !now.Before(m.next)
+}
+
+// Bets of the last fetch, if any
+func (s *pollScheduler) cached(matchID string) (map[string][]Bet, bool) {
+	bets, ok := s.bets[matchID]
+	return bets, ok
+}
+
+// Record a fetch and plan the next one, sooner when the prices changed
+func (s *pollScheduler) fetched(matchID string, matchStart int64, familyBets map[string][]Bet, now time.Time) {
+	signature := betsSignature(familyBets)
+	m, ok := s.matches[matchID]
+	if !ok {
+		m = &matchSchedule{}
+		s.matches[matchID] = m
+	}
+	active := ok && m.signature != signature
+	m.signature = signature
+	m.next = now.Add(config.Schedule.interval(matchStart, active, now))
+	s.bets[matchID] = familyBets
+}
+
+// When the earliest match is due again, zero when none is scheduled
+func (s *pollScheduler) nextDue() time.Time {
+	var next time.Time
+	for _, m := range s.matches {
+		if next.IsZero() || m.next.Before(next) {
+			next = m.next
+		}
+	}
+	return next
+}
+
+// Forget matches no longer listed
+func (s *pollScheduler) keep(matchIDs map[string]bool) {
+	for id := range s.matches {
+		if !matchIDs[id] {
+			delete(s.matches, id)
+			delete(s.bets, id)
+		}
+	}
+}
+
+func betsSignature(familyBets map[string][]Bet) string {
+	var parts []string
+	for family, bets := range familyBets {
+		for _, bet := range bets {
+			parts = append(parts, family+"#"+generateBetSignature(bet))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
+
+// Token bucket shared by every watcher, refilled continuously
+type requestBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+var matchRequests = &requestBudget{tokens: math.Inf(1)}
+
+// Take one request from the budget, false when it is spent
+func (b *requestBudget) take(now time.Time) bool {
+	return b.reserve(now) == 0
+}
+
+// Take one request, waiting for the budget to refill when it is spent. For the
+// requests a cycle can't do without (session, sport page).
+func (b *requestBudget) wait() {
+	for {
+		wait := b.reserve(time.Now())
+		if wait == 0 {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
+
+// Take one request and return 0, or return how long until one is available
+func (b *requestBudget) reserve(now time.Time) time.Duration {
+	perMinute := float64(config.Schedule.RequestsPerMinute)
+	if perMinute <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Minutes() * perMinute
+	}
+	b.tokens = math.Min(b.tokens, perMinute)
+	b.last = now
+	if b.tokens < 1 {
+		return max(time.Duration((1-b.tokens)/perMinute*float64(time.Minute)), time.Millisecond)
+	}
+	b.tokens--
+	return 0
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleInterval(t *testing.T) {
+	c := defaultSchedule()
+	day := time.Date(2026, 5, 10, 14, 0, 0, 0, time.Local)
+	night := time.Date(2026, 5, 10, 3, 0, 0, 0, time.Local)
+	startIn := func(now time.Time, d time.Duration) int64 { return now.Add(d).Unix() }
+
+	tests := []struct {
+		name       string
+		c          ScheduleConfig
+		matchStart int64
+		active     bool
+		now        time.Time
+		want       time.Duration
+	}{
+		{"within the first tier", c, startIn(day, 30*time.Minute), false, day, 15 * time.Second},
+		{"on a tier's edge", c, startIn(day, time.Hour), false, day, 15 * time.Second},
+		{"second tier", c, startIn(day, 3*time.Hour), false, day, time.Minute},
+		{"third tier", c, startIn(day, 20*time.Hour), false, day, 5 * time.Minute},
+		{"beyond every tier", c, startIn(day, 48*time.Hour), false, day, 30 * time.Minute},
+		{"already started", c, startIn(day, -time.Hour), false, day, 15 * time.Second},
+		{"unknown start", c, 0, false, day, 30 * time.Minute},
+		{"active", c, startIn(day, 3*time.Hour), true, day, 30 * time.Second},
+		{"night", c, startIn(night, 3*time.Hour), false, night, 2 * time.Minute},
+		{"active at night", c, startIn(night, 3*time.Hour), true, night, time.Minute},
+		{"no tiers", ScheduleConfig{}, startIn(day, 3*time.Hour), true, day, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.c.interval(tt.matchStart, tt.active, tt.now); got != tt.want {
+			t.Errorf("%s: interval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulePause(t *testing.T) {
+	now := time.Date(2026, 5, 10, 14, 0, 0, 0, time.Local)
+	c := ScheduleConfig{ListEverySeconds: 30}
+
+	tests := []struct {
+		name    string
+		c       ScheduleConfig
+		nextDue time.Time
+		want    time.Duration
+	}{
+		{"nothing due", c, time.Time{}, 30 * time.Second},
+		{"due before the next list", c, now.Add(10 * time.Second), 10 * time.Second},
+		{"due after the next list", c, now.Add(time.Minute), 30 * time.Second},
+		{"overdue", c, now.Add(-time.Minute), MIN_CYCLE_PAUSE},
+		{"due in under the minimum", c, now.Add(time.Second), MIN_CYCLE_PAUSE},
+		{"default list interval", ScheduleConfig{}, time.Time{}, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.c.pause(tt.nextDue, now); got != tt.want {
+			t.Errorf("%s: pause = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleIsNight(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2026, 5, 10, hour, 0, 0, 0, time.Local) }
+	tests := []struct {
+		start, end, hour int
+		want             bool
+	}{
+		{1, 7, 0, false},
+		{1, 7, 1, true},
+		{1, 7, 6, true},
+		{1, 7, 7, false},
+		// Across midnight
+		{22, 6, 23, true},
+		{22, 6, 3, true},
+		{22, 6, 12, false},
+		{0, 0, 3, false},
+	}
+	for _, tt := range tests {
+		c := ScheduleConfig{NightStart: tt.start, NightEnd: tt.end}
+		if got := c.isNight(at(tt.hour)); got != tt.want {
+			t.Errorf("isNight(%d-%d at %dh) = %v, want %v", tt.start, tt.end, tt.hour, got, tt.want)
+		}
+	}
+}
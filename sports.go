@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+)
+
+// A sport followed by its own watcher: Winamax sport ID (route sport:<id>),
+// the market families to extract and where to send them
+type SportConfig struct {
+	Name    string         `json:"name"`    // "tennis", "basketball", "football"...
+	ID      int            `json:"id"`      // Winamax sportId: 5 tennis, 2 basketball, 1 football
+	Page    string         `json:"page"`    // page used for the initial cookies (default: the book's sport page)
+	Emoji   string         `json:"emoji"`   // notification header
+	Channel string         `json:"channel"` // default channel for families without one
+	Markets []MarketFamily `json:"markets"`
+}
+
+// Tennis uses the top-level "markets" list so older config files keep working
+func defaultSports(markets []MarketFamily) []SportConfig {
+	return []SportConfig{
+		{
+			Name:    "tennis",
+			ID:      5,
+			Emoji:   "🎾",
+			Markets: append([]MarketFamily(nil), markets...),
+		},
+	}
+}
+
+// Fill in per-sport defaults and attach the sport to each of its families
+func (s *SportConfig) normalize() {
+	if s.Emoji == "" {
+		s.Emoji = "🏅"
+	}
+	if s.Channel == "" {
+		s.Channel = NEW_BETS_CHANNEL
+	}
+	for i := range s.Markets {
+		if s.Markets[i].Channel == "" {
+			s.Markets[i].Channel = s.Channel
+		}
+		s.Markets[i].sport = s.Name
+		s.Markets[i].emoji = s.Emoji
+	}
+}
+
+// Sport page of a book, e.g. /paris-sportifs/sports/5 on winamax.fr
+func (s SportConfig) pageFor(book BookConfig) string {
+	if s.Page != "" {
+		return s.Page
+	}
+	return fmt.Sprintf(book.SportPage, s.ID)
+}
+
+// Debug dump of the last raw response - tennis on winamax.fr keeps the historical names
+func (s SportConfig) dumpFile(name string, book BookConfig) string {
+	if s.Name != "tennis" {
+		name += "_" + s.Name
+	}
+	if !book.isDefault() {
+		name += "_" + book.Name
+	}
+	return name + ".txt"
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Stake suggestions shown in value alerts
+type StakingConfig struct {
+	Bankroll      float64 `json:"bankroll"`       // in euros, 0 = no suggestion
+	Policy        string  `json:"policy"`         // flat, kelly or percent
+	FlatStake     float64 `json:"flat_stake"`     // flat: euros per option
+	KellyFraction float64 `json:"kelly_fraction"` // kelly: share of the full Kelly stake (0.25 = quarter Kelly)
+	Percent       float64 `json:"percent"`        // percent: share of the bankroll per option (0.01 = 1%)
+	MaxMatchPct   float64 `json:"max_match_pct"`  // cap on the stakes of one alert, as a share of the bankroll (0 = none)
+}
+
+// Stake for one option from its fair probability and offered cote
+func (s StakingConfig) stake(opt Option) float64 {
+	switch strings.ToLower(s.Policy) {
+	case "flat":
+		return s.FlatStake
+	case "percent":
+		return s.Bankroll * s.Percent
+	default:
+		// Kelly: edge / (cote - 1), never negative
+		if opt.Cote <= 1 || opt.Value <= 0 {
+			return 0
+		}
+		return s.Bankroll * s.KellyFraction * opt.Value / (opt.Cote - 1)
+	}
+}
+
+// Stakes for the options of one alert, scaled down together so they stay under
+// the per-match cap, rounded to the euro
+func (s StakingConfig) suggest(options []Option) []float64 {
+	stakes := make([]float64, len(options))
+	if s.Bankroll <= 0 {
+		return stakes
+	}
+	total := 0.0
+	for i, opt := range options {
+		stakes[i] = math.Max(0, s.stake(opt))
+		total += stakes[i]
+	}
+	if limit := s.Bankroll * s.MaxMatchPct; limit > 0 && total > limit {
+		for i := range stakes {
+			stakes[i] *= limit / total
+		}
+	}
+	for i := range stakes {
+		stakes[i] = math.Round(stakes[i])
+	}
+	return stakes
+}
+
+// " → mise 12€" when there is a stake to suggest
+func formatStake(stake float64) string {
+	if stake <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" → mise %.0f€", stake)
+}
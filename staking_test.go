@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSuggestStakes(t *testing.T) {
+	kelly := StakingConfig{Bankroll: 1000, Policy: "kelly", KellyFraction: 0.25, MaxMatchPct: 0.05}
+	fullKelly := kelly
+	fullKelly.KellyFraction = 1
+	uncapped := fullKelly
+	uncapped.MaxMatchPct = 0
+
+	tests := []struct {
+		name    string
+		staking StakingConfig
+		options []Option
+		want    []float64
+	}{
+		// 1000 × 0.25 × 0.2 / (3 - 1)
+		{"quarter kelly under the cap", kelly, []Option{{Cote: 3, Value: 0.2}}, []float64{25}},
+		// 25 + 40 = 65 scaled down to the 50€ cap
+		{"stakes scaled together to the cap", kelly, []Option{{Cote: 3, Value: 0.2}, {Cote: 2, Value: 0.16}}, []float64{19, 31}},
+		// 1000 × 0.3 / 1.5 = 200
+		{"full kelly capped", fullKelly, []Option{{Cote: 2.5, Value: 0.3}}, []float64{50}},
+		{"full kelly without cap", uncapped, []Option{{Cote: 2.5, Value: 0.3}}, []float64{200}},
+		{"no edge", kelly, []Option{{Cote: 2, Value: -0.1}, {Cote: 2, Value: 0}}, []float64{0, 0}},
+		{"cote of 1 or less", kelly, []Option{{Cote: 1, Value: 0.5}}, []float64{0}},
+		{"no bankroll", StakingConfig{Policy: "kelly", KellyFraction: 1, MaxMatchPct: 0.05}, []Option{{Cote: 2.5, Value: 0.3}}, []float64{0}},
+		// The cap applies to every policy: 3 × 10€ scaled to 20€
+		{"flat capped", StakingConfig{Bankroll: 1000, Policy: "flat", FlatStake: 10, MaxMatchPct: 0.02}, []Option{{}, {}, {}}, []float64{7, 7, 7}},
+		{"percent under the cap", StakingConfig{Bankroll: 1000, Policy: "percent", Percent: 0.01, MaxMatchPct: 0.05}, []Option{{}, {}}, []float64{10, 10}},
+	}
+	for _, tt := range tests {
+		if got := tt.staking.suggest(tt.options); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: suggest = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Telegram parse modes a notification template can be written for
+const (
+	PARSE_MODE_HTML        = "HTML"
+	PARSE_MODE_MARKDOWN_V2 = "MarkdownV2"
+)
+
+// Template of the new bet notifications: the default applies to every channel
+// without an entry of its own in Channels (keyed by chat ID)
+type NotificationConfig struct {
+	Default  ChannelTemplate            `json:"default"`
+	Channels map[string]ChannelTemplate `json:"channels"`
+}
+
+// A text/template and the parse mode it is written for. Values are not escaped
+// by text/template - use the esc, bold, link and bet functions, which produce
+// the markup of the parse mode.
+type ChannelTemplate struct {
+	ParseMode    string `json:"parse_mode"`    // HTML (default) or MarkdownV2
+	Template     string `json:"template"`      // inline template, the built-in one when empty
+	TemplateFile string `json:"template_file"` // or a file holding it
+}
+
+// Built-in layout: player Over/Under, match Over/Under, player ladders, match
+// ladders, then other templates, each bet followed by a blank line
+const DEFAULT_NOTIFICATION_TEMPLATE = `{{.Emoji}} {{bold .Title}}
+{{with .Details}}{{esc .}}
+{{end}}
+{{range .PlayerLines}}{{bet .}}
+{{end}}{{range .TotalLines}}{{bet .}}
+{{end}}{{range .PlayerLadders}}{{bet .}}
+{{end}}{{range .TotalLadders}}{{bet .}}
+{{end}}{{range .Others}}{{bet .}}
+{{end}}🔗 {{link .Lien "LIEN"}}`
+
+// Escaping and formatting of one parse mode
+type markup struct {
+	parseMode string
+	escape    func(string) string
+	bold      func(string) string // escapes its text
+	link      func(url, text string) string
+}
+
+var htmlMarkup = markup{
+	parseMode: PARSE_MODE_HTML,
+	escape:    html.EscapeString,
+	bold:      func(s string) string { return "<b>" + html.EscapeString(s) + "</b>" },
+	link: func(url, text string) string {
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(url), html.EscapeString(text))
+	},
+}
+
+// Every character MarkdownV2 reserves must be backslash-escaped outside entities
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// Inside the (...) of a link only ) and \ are escaped
+var markdownV2URLReplacer = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+var markdownV2Markup = markup{
+	parseMode: PARSE_MODE_MARKDOWN_V2,
+	escape:    markdownV2Replacer.Replace,
+	bold:      func(s string) string { return "*" + markdownV2Replacer.Replace(s) + "*" },
+	link: func(url, text string) string {
+		return "[" + markdownV2Replacer.Replace(text) + "](" + markdownV2URLReplacer.Replace(url) + ")"
+	},
+}
+
+func markupFor(parseMode string) markup {
+	if strings.EqualFold(parseMode, PARSE_MODE_MARKDOWN_V2) {
+		return markdownV2Markup
+	}
+	return htmlMarkup
+}
+
+// What a notification template sees: the match, its header and its new bets
+// grouped in the built-in order (all of them in Bets, in that order too)
+type notificationView struct {
+	Family  string
+	Emoji   string
+	Match   Match
+	Title   string // players, with doubles pairs spelled out
+	Details string // tournament, round and start
+	Lien    string
+
+	Bets          []Bet
+	PlayerLines   []Bet
+	TotalLines    []Bet
+	PlayerLadders []Bet
+	TotalLadders  []Bet
+	Others        []Bet
+}
+
+func newNotificationView(family MarketFamily, match Match, bets []Bet) notificationView {
+	view := notificationView{
+		Family:  family.Name,
+		Emoji:   family.emoji,
+		Match:   match,
+		Title:   match.displayTitle(),
+		Details: match.details(),
+		Lien:    match.Lien,
+	}
+	for _, bet := range bets {
+		player := betSide(bet, match) != 0
+		switch {
+		case bet.isOverUnder() && player:
+			view.PlayerLines = append(view.PlayerLines, bet)
+		case bet.isOverUnder():
+			view.TotalLines = append(view.TotalLines, bet)
+		case bet.isLadder() && player:
+			view.PlayerLadders = append(view.PlayerLadders, bet)
+		case bet.isLadder():
+			view.TotalLadders = append(view.TotalLadders, bet)
+		default:
+			view.Others = append(view.Others, bet)
+		}
+	}
+	for _, group := range [][]Bet{view.PlayerLines, view.TotalLines, view.PlayerLadders, view.TotalLadders, view.Others} {
+		view.Bets = append(view.Bets, group...)
+	}
+	return view
+}
+
+// A parsed template with the markup its functions write
+type channelTemplate struct {
+	tmpl   *template.Template
+	markup markup
+}
+
+// Parse a channel's template, reading its file when it has one
+func parseChannelTemplate(name string, ct ChannelTemplate) (channelTemplate, error) {
+	mk := markupFor(ct.ParseMode)
+	text := ct.Template
+	if ct.TemplateFile != "" {
+		data, err := os.ReadFile(ct.TemplateFile)
+		if err != nil {
+			return channelTemplate{}, err
+		}
+		text = string(data)
+	}
+	if strings.TrimSpace(text) == "" {
+		text = DEFAULT_NOTIFICATION_TEMPLATE
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"esc":  mk.escape,
+		"bold": mk.bold,
+		"link": mk.link,
+		"bet":  func(bet Bet) string { return formatBetAs(bet, mk) },
+	}).Parse(text)
+	if err != nil {
+		return channelTemplate{}, err
+	}
+	return channelTemplate{tmpl: tmpl, markup: mk}, nil
+}
+
+// Parsed templates per channel, built once from the config
+type notificationTemplates struct {
+	mu       sync.Mutex
+	builtin  channelTemplate
+	fallback channelTemplate
+	channels map[string]channelTemplate
+}
+
+var notificationFormats = newNotificationTemplates()
+
+func newNotificationTemplates() *notificationTemplates {
+	builtin, _ := parseChannelTemplate("builtin", ChannelTemplate{})
+	return &notificationTemplates{builtin: builtin, fallback: builtin, channels: make(map[string]channelTemplate)}
+}
+
+// Parse the configured templates. A template that doesn't parse is reported
+// and left out - its channel gets the default one - so notifications keep going out.
+func (n *notificationTemplates) load(cfg NotificationConfig, lg *slog.Logger) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fallback = n.builtin
+	if parsed, err := parseChannelTemplate("default", cfg.Default); err != nil {
+		lg.Error("Invalid notification template, using the built-in one", "channel", "default", "err", err)
+	} else {
+		n.fallback = parsed
+	}
+	n.channels = make(map[string]channelTemplate)
+	for channel, ct := range cfg.Channels {
+		parsed, err := parseChannelTemplate(channel, ct)
+		if err != nil {
+			lg.Error("Invalid notification template, using the default one", "channel", channel, "err", err)
+			continue
+		}
+		n.channels[channel] = parsed
+	}
+}
+
+// Render the notification for a channel with its parse mode. A template that
+// fails on this data falls back to the built-in layout in HTML.
+func (n *notificationTemplates) render(channel string, view notificationView) (string, string, error) {
+	n.mu.Lock()
+	ct, ok := n.channels[channel]
+	if !ok {
+		ct = n.fallback
+	}
+	builtin := n.builtin
+	n.mu.Unlock()
+
+	var out strings.Builder
+	err := ct.tmpl.Execute(&out, view)
+	if err == nil {
+		return out.String(), ct.markup.parseMode, nil
+	}
+	out.Reset()
+	if builtinErr := builtin.tmpl.Execute(&out, view); builtinErr != nil {
+		return "", "", builtinErr
+	}
+	return out.String(), builtin.markup.parseMode, err
+}
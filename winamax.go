@@ -0,0 +1,760 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	jsonrepair "github.com/RealAlexandreAI/json-repair"
+	http "github.com/bogdanfinn/fhttp"
+	tls_client "github.com/bogdanfinn/tls-client"
+	"github.com/bogdanfinn/tls-client/profiles"
+	"github.com/google/uuid"
+)
+
+// One sport of one Winamax site, scraped over socket.io with a new proxy and session every cycle
+type winamaxBook struct {
+	sport    SportConfig
+	book     BookConfig
+	markets  []MarketFamily
+	proxies  []string
+	schedule *pollScheduler // only used by Fetch, from the watcher's goroutine
+}
+
+func newWinamaxBook(sport SportConfig, book BookConfig, proxies []string) *winamaxBook {
+	return &winamaxBook{
+		sport:    sport,
+		book:     book,
+		markets:  sport.marketsFor(book),
+		proxies:  proxies,
+		schedule: newPollScheduler(),
+	}
+}
+
+func (w *winamaxBook) Name() string {
+	return w.book.Name
+}
+
+func (w *winamaxBook) ID() string {
+	return w.sport.Name + "_" + w.book.Name
+}
+
+func (w *winamaxBook) Markets() []MarketFamily {
+	return w.markets
+}
+
+func (w *winamaxBook) Silent() bool {
+	return w.book.Silent
+}
+
+// Until the next followed match is due, the sport page being read at least every list_every_seconds
+func (w *winamaxBook) NextCycle(now time.Time) time.Duration {
+	return config.Schedule.pause(w.schedule.nextDue(), now)
+}
+
+// Open a session, read the sport page and fetch every match a market family wants
+func (w *winamaxBook) Fetch(lg *slog.Logger) (FetchResult, error) {
+	book, sport := w.book, w.sport
+
+	session, err := w.openSession(lg)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	client, sid, proxyName := session.client, session.sid, session.proxy
+	lg = lg.With("proxy", proxyName)
+
+	requestId := uuid.New().String()
+
+	// First data
+	route := fmt.Sprintf("sport:%d", sport.ID)
+	postData := socketIOPacket(fmt.Sprintf(`42["m",{"route":"%s","requestId":"%s"}]`, route, requestId))
+
+	subscribeLog := lg.With("stage", "subscribe")
+	if err := postSubscription(client, book, sid, postData, subscribeLog); err != nil {
+		return FetchResult{}, &fetchError{stage: "subscribe", proxy: proxyName, message: "❌ Échec subscription", err: err}
+	}
+
+	if _, err := getFinalData(client, book, sid); err != nil {
+		return FetchResult{}, &fetchError{stage: "subscribe", proxy: proxyName, message: "❌ Échec données finales", err: err}
+	}
+	subscribeLog.Debug("Got first data")
+
+	clientTime := time.Now().UnixMilli()
+	postData = socketIOPacket(fmt.Sprintf(`42["m",{"requestId":"%s","route":"%s","data":true,"menu":true,"clientTime":%d}]`, requestId, route, clientTime))
+
+	// Data with categories
+	sportLog := lg.With("stage", "sport")
+	matchRequests.wait()
+	if err := postSubscription(client, book, sid, postData, sportLog); err != nil {
+		return FetchResult{}, &fetchError{stage: "sport", proxy: proxyName, message: "❌ Échec subscription catégories", err: err}
+	}
+
+	finalData, err := getFinalData(client, book, sid)
+	if err != nil {
+		return FetchResult{}, &fetchError{stage: "sport", proxy: proxyName, message: "Error fetching final data", err: err}
+	}
+
+	if err := os.WriteFile(sport.dumpFile("final_response", book), finalData, 0644); err != nil {
+		return FetchResult{}, &fetchError{stage: "sport", proxy: proxyName, message: "Error writing response to file", err: err}
+	}
+
+	// Filter Matches
+	parseLog := lg.With("stage", "parse")
+	jsonStr := string(finalData)
+	jsonStr = strings.TrimSpace(jsonStr)
+	if strings.HasPrefix(jsonStr, "//") {
+		jsonStr = jsonStr[strings.Index(jsonStr, "\n")+1:]
+	}
+
+	// Remove numeric prefix before every ["m", and any ] before that
+	re := regexp.MustCompile(`\](\d+:)?\["m",`)
+	jsonStr = re.ReplaceAllString(jsonStr, `,["m",`)
+
+	// Remove numeric prefix at the start (if any)
+	if idx := strings.Index(jsonStr, "["); idx > 0 {
+		jsonStr = jsonStr[idx:]
+	}
+
+	jsonStr = strings.TrimSpace(jsonStr)
+	if !strings.HasSuffix(jsonStr, "]]") {
+		jsonStr += "]]"
+	}
+
+	jsonStr, err = jsonrepair.RepairJSON(jsonStr)
+	if err != nil {
+		return FetchResult{}, &fetchError{stage: "parse", proxy: proxyName, message: "⚠️ Erreur réparation JSON - retry...", err: err, soft: true}
+	}
+
+	var arr []interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &arr); err != nil {
+		return FetchResult{}, &fetchError{stage: "parse", proxy: proxyName, message: "⚠️ Erreur parsing JSON - retry...", err: err, soft: true}
+	}
+	if len(arr) < 2 {
+		return FetchResult{}, &fetchError{stage: "parse", proxy: proxyName, message: "⚠️ Structure JSON inattendue - retry...", err: fmt.Errorf("%d elements", len(arr)), soft: true}
+	}
+	root, ok := arr[1].(map[string]interface{})
+	if !ok {
+		return FetchResult{}, &fetchError{stage: "parse", proxy: proxyName, message: "⚠️ Format réponse invalide - retry...", soft: true}
+	}
+
+	matches, _ := root["matches"].(map[string]interface{})
+	if matches == nil {
+		return FetchResult{}, &fetchError{stage: "parse", proxy: proxyName, message: "⚠️ Pas de 'matches' dans la réponse - retry...", soft: true}
+	}
+
+	// Tournament and category names, keyed by the IDs the matches carry
+	tournaments, _ := root["tournaments"].(map[string]interface{})
+	categories, _ := root["categories"].(map[string]interface{})
+
+	// loop all matches - get filters key, and keep the ones a market family wants (548 = aces)
+	// or whose notified prices are followed until the start. Started matches are
+	// left to the live watcher.
+	pending := clv.pending(book.Name)
+	started := make(map[string]bool)
+	for _, m := range liveMatches.get(w.ID()) {
+		started[m.MatchID] = true
+	}
+	result := FetchResult{Families: make(map[string][]MatchData)}
+	var matchIDs []float64
+	matchFamilies := make(map[float64][]MarketFamily)
+	matchInfo := make(map[float64]Match)
+	for _, m := range matches {
+		match, _ := m.(map[string]interface{})
+		matchID, _ := match["matchId"].(float64)
+		if matchID == 0 {
+			parseLog.Debug("Match ID is empty for a match")
+			continue
+		}
+
+		filterList, hasFilters := match["filters"].([]interface{})
+		if !hasFilters {
+			parseLog.Debug("No filters found for a match", "matchId", fmt.Sprintf("%.0f", matchID))
+		}
+
+		// Every listed match feeds the lifecycle, followed or not
+		status := MatchStatus{
+			Match: Match{
+				MatchID: fmt.Sprintf("%.0f", matchID),
+				Lien:    book.matchLink(fmt.Sprintf("%.0f", matchID)),
+			},
+		}
+		status.Joueurs, _ = match["title"].(string)
+		if id, ok := match["competitor1Id"].(float64); ok {
+			status.Competitor1ID = int64(id)
+		}
+		if id, ok := match["competitor2Id"].(float64); ok {
+			status.Competitor2ID = int64(id)
+		}
+		name1, _ := match["competitor1Name"].(string)
+		name2, _ := match["competitor2Name"].(string)
+		players.recordMatch(status.Match, name1, name2)
+		status.Status, _ = match["status"].(string)
+		status.Available, _ = match["available"].(bool)
+		if period, ok := match["period"]; ok && period != nil {
+			status.Period = fmt.Sprint(period)
+		}
+		if start, ok := match["matchStart"].(float64); ok {
+			status.MatchStart = int64(start)
+		}
+		status.Tour, _ = match["roundName"].(string)
+		if id, ok := match["tournamentId"].(float64); ok {
+			status.TournamentID = int64(id)
+			tournament, _ := tournaments[fmt.Sprintf("%.0f", id)].(map[string]interface{})
+			status.Tournoi, _ = tournament["tournamentName"].(string)
+		}
+		if id, ok := match["categoryId"].(float64); ok {
+			category, _ := categories[fmt.Sprintf("%.0f", id)].(map[string]interface{})
+			status.Categorie, _ = category["categoryName"].(string)
+		}
+
+		followed := pending[status.MatchID]
+		var wanted []MarketFamily
+		for _, family := range w.markets {
+			if (hasFilters && family.wantsMatch(filterList) && family.wantsTournament(status.Match)) || slices.Contains(followed, family.Name) {
+				wanted = append(wanted, family)
+			}
+		}
+		status.HasMarkets = len(wanted) > 0
+		result.Matches = append(result.Matches, status)
+		matchInfo[matchID] = status.Match
+
+		if len(wanted) == 0 || started[status.MatchID] {
+			continue
+		}
+		matchIDs = append(matchIDs, matchID)
+		matchFamilies[matchID] = wanted
+	}
+
+	if len(matchIDs) > 0 {
+		parseLog.Info(fmt.Sprintf("%s %d match(s) avec marchés suivis trouvé(s)", sport.Emoji, len(matchIDs)), "matches", len(matchIDs))
+	} else {
+		parseLog.Debug("No matches with followed markets found")
+	}
+
+	// Closest matches first, so they get the request budget when it runs short
+	sort.Slice(matchIDs, func(i, j int) bool {
+		a, b := matchInfo[matchIDs[i]].MatchStart, matchInfo[matchIDs[j]].MatchStart
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+
+	// Fetch Data for each match that is due - results are kept per market family.
+	// Matches not due (or over budget) reuse their last fetch; a failed match
+	// without one is skipped, the others still make it into this cycle.
+	listed := make(map[string]bool)
+	fetchedCount := 0
+	for _, matchID := range matchIDs {
+		matchIDStr := fmt.Sprintf("%.0f", matchID)
+		matchLog := lg.With("stage", "match", "matchId", matchIDStr)
+		listed[matchIDStr] = true
+
+		familyBets, ok := w.schedule.cached(matchIDStr)
+		if now := time.Now(); !ok || w.schedule.due(matchIDStr, now) {
+			if matchRequests.take(now) {
+				if fresh, fetched := w.fetchMatchBets(session, matchIDStr, matchLog); fetched {
+					familyBets, ok = fresh, true
+					w.schedule.fetched(matchIDStr, matchInfo[matchID].MatchStart, fresh, now)
+					fetchedCount++
+				}
+			} else {
+				matchLog.Debug("Request budget spent, keeping last fetch")
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		// One entry per family that wanted this match, even without bets yet
+		match := matchInfo[matchID]
+		for _, family := range matchFamilies[matchID] {
+			// Link player bets to their competitor, then sort them in display order
+			matchData := MatchData{
+				Match: match,
+				Bet:   sortBets(players.link(familyBets[family.Name], match), match),
+			}
+
+			result.Families[family.Name] = append(result.Families[family.Name], matchData)
+		}
+	}
+
+	w.schedule.keep(listed)
+	if err := players.save(); err != nil {
+		lg.Error("Error saving player registry", "stage", "players", "file", PLAYERS_FILE, "err", err)
+	}
+	lg.Debug("Matches fetched", "stage", "schedule", "fetched", fetchedCount, "followed", len(matchIDs))
+
+	return result, nil
+}
+
+// A socket.io session on one proxy: cookies, SID and handshake done
+type winamaxSession struct {
+	client tls_client.HttpClient
+	sid    string
+	proxy  string
+}
+
+// New client on a random proxy, session cookies from the sport page, then the
+// socket.io SID. Counts as one request of the budget.
+func (w *winamaxBook) openSession(lg *slog.Logger) (*winamaxSession, error) {
+	book, sport := w.book, w.sport
+	matchRequests.wait()
+
+	// Get a random proxy for this cycle
+	currentProxy := getRandomProxy(w.proxies)
+	proxyName := getProxyShortName(currentProxy)
+
+	jar := tls_client.NewCookieJar()
+	options := []tls_client.HttpClientOption{
+		tls_client.WithTimeoutSeconds(30),
+		tls_client.WithClientProfile(profiles.Chrome_133),
+		tls_client.WithCookieJar(jar),
+	}
+
+	// Add proxy if available
+	if currentProxy != "" {
+		options = append(options, tls_client.WithProxyUrl(currentProxy))
+	}
+
+	client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
+	if err != nil {
+		return nil, &fetchError{stage: "client", proxy: proxyName, message: "Client error", err: err}
+	}
+
+	// get init page session cookies
+	if err := getInitialCookies(client, book, sport.pageFor(book)); err != nil {
+		return nil, &fetchError{stage: "cookies", proxy: proxyName, message: "❌ Échec cookies", err: err}
+	}
+
+	// get socketio session id
+	sid, err := getSessionID(client, book, lg.With("stage", "sid"))
+	if err != nil {
+		return nil, &fetchError{stage: "sid", proxy: proxyName, message: "❌ Échec SID", err: err}
+	}
+
+	// do first request and get 2:40 back
+	if _, err := getInitialDataWithSID(client, book, sid); err != nil {
+		return nil, &fetchError{stage: "handshake", proxy: proxyName, message: "❌ Échec données initiales", err: err}
+	}
+
+	return &winamaxSession{client: client, sid: sid, proxy: proxyName}, nil
+}
+
+// Subscribe to a match and extract the priced bets of the followed families.
+// Failures are logged and reported as not ok so the caller skips the match.
+func (w *winamaxBook) fetchMatchBets(s *winamaxSession, matchID string, lg *slog.Logger) (map[string][]Bet, bool) {
+	payload := socketIOPacket(fmt.Sprintf(`42["m",{"route":"match:%s","data":true,"menu":true,"clientTime":%d}]`, matchID, time.Now().UnixMilli()))
+	if err := postSubscription(s.client, w.book, s.sid, payload, lg); err != nil {
+		if !isProxyError(err) {
+			lg.Warn("POST subscription for match failed", "err", err)
+		} else {
+			lg.Warn("⚠️ Proxy error on match request, skipping...", "err", err)
+			digest.proxySwitched()
+		}
+		return nil, false
+	}
+
+	finalData, err := getFinalData(s.client, w.book, s.sid)
+	if err != nil {
+		lg.Warn("Error fetching final data for match", "err", err)
+		return nil, false
+	}
+
+	// extract data
+	jsonStr := string(finalData)
+	jsonStr = strings.TrimSpace(jsonStr)
+	if strings.HasPrefix(jsonStr, "//") {
+		jsonStr = jsonStr[strings.Index(jsonStr, "\n")+1:]
+	}
+
+	// Remove numeric prefix before every ["m", and any ] before that
+	re := regexp.MustCompile(`\]?\d+:\d+\["m",`)
+	jsonStr = re.ReplaceAllString(jsonStr, `,["m",`)
+
+	// Remove numeric prefix at the start (if any)
+	if idx := strings.Index(jsonStr, "["); idx > 0 {
+		jsonStr = jsonStr[idx:]
+	}
+
+	jsonStr = strings.TrimSpace(jsonStr)
+	if !strings.HasSuffix(jsonStr, "]]") {
+		jsonStr += "]]"
+	}
+
+	jsonStr, err = jsonrepair.RepairJSON(jsonStr)
+	if err != nil {
+		lg.Warn("Failed to repair JSON", "err", err)
+		return nil, false
+	}
+
+	if err := os.WriteFile(w.sport.dumpFile("match_response", w.book), []byte(jsonStr), 0644); err != nil {
+		lg.Error("Error writing response to file", "err", err)
+	}
+
+	var arr []interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &arr); err != nil {
+		lg.Warn("Failed to unmarshal JSON", "err", err)
+		return nil, false
+	}
+	if len(arr) < 2 {
+		lg.Warn("Unexpected JSON structure, expected at least 2 elements", "elements", len(arr))
+		return nil, false
+	}
+
+	bets, outcomes, odds, ok := findLargestBetBlock(arr)
+	if !ok {
+		lg.Warn("No complete bet block found in the response")
+		return nil, false
+	}
+	lg.Debug("Bets found", "bets", len(bets))
+
+	familyBets := make(map[string][]Bet)
+	for _, b := range bets {
+		if b == nil {
+			continue
+		}
+		bet, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// keep only bets belonging to a followed market family
+		betTypeName, _ := bet["betTypeName"].(string)
+		betTypeID, _ := bet["betType"].(float64)
+		family, ok := familyFor(w.markets, betTypeName, int(betTypeID))
+		if !ok {
+			continue
+		}
+
+		extracted, err := extractBet(bet, outcomes, odds)
+		if err != nil {
+			lg.Debug("Skipping bet", "bet", betTypeName, "err", err)
+			continue
+		}
+		familyBets[family.Name] = append(familyBets[family.Name], extracted)
+	}
+
+	return familyBets, true
+}
+
+func getInitialCookies(client tls_client.HttpClient, book BookConfig, page string) error {
+	url := book.BaseURL + page
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	hostName := extractHostname(book.BaseURL)
+	req.Header = http.Header{
+		"Host":                      {hostName},
+		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Upgrade-Insecure-Requests": {"1"},
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		"Sec-Fetch-Site":            {"none"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-User":            {"?1"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Accept-Encoding":           {"gzip, deflate, br"},
+		"Accept-Language":           {book.AcceptLanguage},
+		http.HeaderOrderKey: {
+			"Host",
+			"Sec-Ch-Ua",
+			"Sec-Ch-Ua-Mobile",
+			"Sec-Ch-Ua-Platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("initial request failed with status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func getSessionID(client tls_client.HttpClient, book BookConfig, lg *slog.Logger) (string, error) {
+	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
+	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=%s&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s", book.SocketURL, book.Language, t)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+
+	hostName := extractHostname(book.BaseURL)
+	req.Header = http.Header{
+		"Host":                      {hostName},
+		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Upgrade-Insecure-Requests": {"1"},
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		"Sec-Fetch-Site":            {"none"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-User":            {"?1"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Accept-Encoding":           {"gzip, deflate, br"},
+		"Accept-Language":           {book.AcceptLanguage},
+		http.HeaderOrderKey: {
+			"Host",
+			"Sec-Ch-Ua",
+			"Sec-Ch-Ua-Mobile",
+			"Sec-Ch-Ua-Platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	re := regexp.MustCompile(`\d+:\d+({.*})`)
+	match := re.FindStringSubmatch(string(body))
+	if len(match) < 2 {
+		return "", fmt.Errorf("SID JSON not found in response")
+	}
+
+	var sidData struct {
+		SID string `json:"sid"`
+	}
+	if err := json.NewDecoder(strings.NewReader(match[1])).Decode(&sidData); err != nil {
+		return "", fmt.Errorf("failed to decode SID JSON: %w", err)
+	}
+
+	lg.Debug("Extracted SID", "sid", sidData.SID)
+	return sidData.SID, nil
+}
+
+func getInitialDataWithSID(client tls_client.HttpClient, book BookConfig, sid string) ([]byte, error) {
+	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
+	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=%s&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", book.SocketURL, book.Language, t, sid)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hostName := extractHostname(book.BaseURL)
+	req.Header = http.Header{
+		"Host":                      {hostName},
+		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Upgrade-Insecure-Requests": {"1"},
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		"Sec-Fetch-Site":            {"none"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-User":            {"?1"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Accept-Encoding":           {"gzip, deflate, br"},
+		"Accept-Language":           {book.AcceptLanguage},
+		http.HeaderOrderKey: {
+			"Host",
+			"Sec-Ch-Ua",
+			"Sec-Ch-Ua-Mobile",
+			"Sec-Ch-Ua-Platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func postSubscription(client tls_client.HttpClient, book BookConfig, sid, payload string, lg *slog.Logger) error {
+	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
+	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=%s&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", book.SocketURL, book.Language, t, sid)
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	hostName := extractHostname(book.BaseURL)
+	req.Header = http.Header{
+		"Host":                      {hostName},
+		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Upgrade-Insecure-Requests": {"1"},
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		"Sec-Fetch-Site":            {"none"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-User":            {"?1"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Accept-Encoding":           {"gzip, deflate, br"},
+		"Accept-Language":           {book.AcceptLanguage},
+		http.HeaderOrderKey: {
+			"Host",
+			"Sec-Ch-Ua",
+			"Sec-Ch-Ua-Mobile",
+			"Sec-Ch-Ua-Platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	lg.Debug("POST subscription body", "body", string(body))
+	if !strings.Contains(string(body), "ok") {
+		if !strings.Contains(string(body), "matches") {
+			return fmt.Errorf("POST subscription failed")
+		}
+	}
+	return nil
+}
+
+// Prefix a socket.io message with its length as engine.io polling expects ("78:42[...]")
+func socketIOPacket(message string) string {
+	return fmt.Sprintf("%d:%s", len(utf16.Encode([]rune(message))), message)
+}
+
+func getFinalData(client tls_client.HttpClient, book BookConfig, sid string) ([]byte, error) {
+	t := fmt.Sprintf("%d", time.Now().UnixNano()/1e6)
+	url := fmt.Sprintf("%s/uof-sports-server/socket.io/?language=%s&version=3.15.1&embed=false&EIO=3&transport=polling&t=%s&sid=%s", book.SocketURL, book.Language, t, sid)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hostName := extractHostname(book.BaseURL)
+	req.Header = http.Header{
+		"Host":                      {hostName},
+		"Sec-Ch-Ua":                 {`"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Upgrade-Insecure-Requests": {"1"},
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		"Sec-Fetch-Site":            {"none"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-User":            {"?1"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Accept-Encoding":           {"gzip, deflate, br"},
+		"Accept-Language":           {book.AcceptLanguage},
+		http.HeaderOrderKey: {
+			"Host",
+			"Sec-Ch-Ua",
+			"Sec-Ch-Ua-Mobile",
+			"Sec-Ch-Ua-Platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return bodyBytes, nil
+}
+
+func findLargestBetBlock(node interface{}) (bets, outcomes, odds map[string]interface{}, found bool) {
+	var maxLen int
+
+	var walk func(interface{})
+	walk = func(n interface{}) {
+		switch v := n.(type) {
+		case map[string]interface{}:
+			if b, ok := v["bets"].(map[string]interface{}); ok {
+				if len(b) > maxLen {
+					if o, ok1 := v["outcomes"].(map[string]interface{}); ok1 {
+						if oddsMap, ok2 := v["odds"].(map[string]interface{}); ok2 {
+							// Save the biggest block with bets, outcomes, and odds
+							maxLen = len(b)
+							bets = b
+							outcomes = o
+							odds = oddsMap
+							found = true
+						}
+					}
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+
+	walk(node)
+	return
+}